@@ -30,10 +30,28 @@ const (
 	JSApiConsumerDeleteT                   = "$JS.API.CONSUMER.DELETE.%s.%s"
 	JSApiRequestNextT                      = "$JS.API.CONSUMER.MSG.NEXT.%s.%s"
 	JSApiConsumerLeaderStepDownT           = "$JS.API.CONSUMER.LEADER.STEPDOWN.%s.%s"
+	JSApiConsumerPauseT                    = "$JS.API.CONSUMER.PAUSE.%s.%s"
 	JSMetricConsumerAckPre                 = JSMetricPrefix + ".CONSUMER.ACK"
 	JSAdvisoryConsumerMaxDeliveryExceedPre = JSAdvisoryPrefix + ".CONSUMER.MAX_DELIVERIES"
+	JSAdvisoryConsumerLeaderElectedPre     = JSAdvisoryPrefix + ".CONSUMER.LEADER_ELECTED"
+	JSAdvisoryConsumerMsgNakedPre          = JSAdvisoryPrefix + ".CONSUMER.MSG_NAKED"
 )
 
+// Headers sent by the server on status messages in response to pull requests
+const (
+	// JSPullRequestPendingMsgs is the number of messages the pull request still had outstanding
+	JSPullRequestPendingMsgs = "Nats-Pending-Messages"
+
+	// JSPullRequestPendingBytes is the number of bytes the pull request still had outstanding
+	JSPullRequestPendingBytes = "Nats-Pending-Bytes"
+)
+
+// JSMsgSize is the size of the original message body delivered by headers only consumers
+const JSMsgSize = "Nats-Msg-Size"
+
+// JSStreamSource is set on messages copied from a source stream and identifies the origin stream and sequence
+const JSStreamSource = "Nats-Stream-Source"
+
 type ConsumerAction int
 
 const (
@@ -142,6 +160,19 @@ type JSApiConsumerLeaderStepDownResponse struct {
 	Success bool `json:"success,omitempty"`
 }
 
+// io.nats.jetstream.api.v1.consumer_pause_request
+type JSApiConsumerPauseRequest struct {
+	PauseUntil *time.Time `json:"pause_until,omitempty"`
+}
+
+// io.nats.jetstream.api.v1.consumer_pause_response
+type JSApiConsumerPauseResponse struct {
+	JSApiResponse
+	Paused         bool          `json:"paused"`
+	PauseUntil     time.Time     `json:"pause_until"`
+	PauseRemaining time.Duration `json:"pause_remaining,omitempty"`
+}
+
 type AckPolicy int
 
 const (
@@ -233,6 +264,55 @@ func (p ReplayPolicy) MarshalJSON() ([]byte, error) {
 	}
 }
 
+type PriorityPolicy int
+
+const (
+	PriorityNone PriorityPolicy = iota
+	PriorityOverflow
+	PriorityPinnedClient
+)
+
+func (p PriorityPolicy) String() string {
+	switch p {
+	case PriorityNone:
+		return "None"
+	case PriorityOverflow:
+		return "Overflow"
+	case PriorityPinnedClient:
+		return "Pinned Client"
+	default:
+		return "Unknown Priority Policy"
+	}
+}
+
+func (p *PriorityPolicy) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case jsonString("none"):
+		*p = PriorityNone
+	case jsonString("overflow"):
+		*p = PriorityOverflow
+	case jsonString("pinned_client"):
+		*p = PriorityPinnedClient
+	default:
+		return fmt.Errorf("unknown priority policy: %v", string(data))
+	}
+
+	return nil
+}
+
+func (p PriorityPolicy) MarshalJSON() ([]byte, error) {
+	switch p {
+	case PriorityNone:
+		return json.Marshal("none")
+	case PriorityOverflow:
+		return json.Marshal("overflow")
+	case PriorityPinnedClient:
+		return json.Marshal("pinned_client")
+	default:
+		return nil, fmt.Errorf("unknown priority policy %v", p)
+	}
+}
+
 var (
 	AckAck      = []byte("+ACK")
 	AckNak      = []byte("-NAK")
@@ -343,6 +423,12 @@ type ConsumerConfig struct {
 	MemoryStorage      bool            `json:"mem_storage,omitempty"`
 	// Metadata is additional metadata for the Consumer.
 	Metadata map[string]string `json:"metadata,omitempty"`
+	// PauseUntil is the time until which the consumer will not deliver messages
+	PauseUntil *time.Time `json:"pause_until,omitempty"`
+	// PriorityGroups are the groups pull requests can be made against when using a PriorityPolicy
+	PriorityGroups []string `json:"priority_groups,omitempty"`
+	// PriorityPolicy is the policy used to select which waiting pull requests receive messages
+	PriorityPolicy PriorityPolicy `json:"priority_policy,omitempty"`
 
 	// Don't add to general clients.
 	Direct bool `json:"direct,omitempty"`
@@ -369,6 +455,8 @@ type ConsumerInfo struct {
 	NumPending     uint64         `json:"num_pending"`
 	Cluster        *ClusterInfo   `json:"cluster,omitempty"`
 	PushBound      bool           `json:"push_bound,omitempty"`
+	Paused         bool           `json:"paused,omitempty"`
+	PauseRemaining time.Duration  `json:"pause_remaining,omitempty"`
 	TimeStamp      time.Time      `json:"ts"`
 }
 
@@ -381,6 +469,13 @@ type JSApiConsumerGetNextRequest struct {
 	MaxBytes  int           `json:"max_bytes,omitempty"`
 	NoWait    bool          `json:"no_wait,omitempty"`
 	Heartbeat time.Duration `json:"idle_heartbeat,omitempty"`
+
+	// Group is the priority group the request is made against
+	Group string `json:"group,omitempty"`
+	// MinPending requests messages only once the consumer has at least this many pending messages when using the Overflow priority policy
+	MinPending uint64 `json:"min_pending,omitempty"`
+	// MinAckPending requests messages only once the consumer has at least this many messages awaiting acknowledgement when using the Overflow priority policy
+	MinAckPending uint64 `json:"min_ack_pending,omitempty"`
 }
 
 // ConsumerNakOptions is for optional NAK parameters, e.g. delay.
@@ -166,6 +166,25 @@ func (e ApiError) ErrorCode() int { return e.Code }
 // NatsErrorCode is the unique nats error code, see `nats errors` command
 func (e ApiError) NatsErrorCode() uint16 { return e.ErrCode }
 
+// Is supports errors.Is() by matching another ApiError with the same nats error code
+func (e ApiError) Is(target error) bool {
+	var code uint16
+
+	switch t := target.(type) {
+	case ApiError:
+		code = t.ErrCode
+	case *ApiError:
+		if t == nil {
+			return false
+		}
+		code = t.ErrCode
+	default:
+		return false
+	}
+
+	return code != 0 && code == e.ErrCode
+}
+
 type JSApiResponse struct {
 	Type  string    `json:"type"`
 	Error *ApiError `json:"error,omitempty"`
@@ -165,6 +165,28 @@ func NewMessage(schemaType string) (any, bool) {
 	return gf(), ok
 }
 
+// KnownSchemaTypes is a sorted list of all the schema types that can be decoded into a typed structure
+func KnownSchemaTypes() []string {
+	types := make([]string, 0, len(schemaTypes))
+	for s := range schemaTypes {
+		types = append(types, s)
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+// NewEventByType creates a new instance of the structure matching schemaType, unlike NewMessage unknown types are an error
+func NewEventByType(schemaType string) (any, error) {
+	msg, ok := NewMessage(schemaType)
+	if !ok {
+		return nil, fmt.Errorf("unknown schema type %q", schemaType)
+	}
+
+	return msg, nil
+}
+
 // ParseMessage parses a typed message m and returns event as for example *api.ConsumerAckMetric, all unknown
 // event schemas will be of type *UnknownMessage
 func ParseMessage(m []byte) (schemaType string, msg any, err error) {
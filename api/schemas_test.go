@@ -160,6 +160,37 @@ func TestSchemaSearch(t *testing.T) {
 	}
 }
 
+func TestKnownSchemaTypes(t *testing.T) {
+	known := KnownSchemaTypes()
+	if len(known) != len(schemaTypes) {
+		t.Fatalf("Expected %d types got %d", len(schemaTypes), len(known))
+	}
+
+	for i := 1; i < len(known); i++ {
+		if known[i-1] > known[i] {
+			t.Fatalf("Expected sorted types got %v", known)
+		}
+	}
+}
+
+func TestNewEventByType(t *testing.T) {
+	event, err := NewEventByType("io.nats.jetstream.advisory.v1.consumer_leader_elected")
+	checkErr(t, err, "new failed")
+	if _, ok := event.(*jsadvisory.JSConsumerLeaderElectedV1); !ok {
+		t.Fatalf("Expected JSConsumerLeaderElectedV1 got %T", event)
+	}
+
+	other, _ := NewEventByType("io.nats.jetstream.advisory.v1.consumer_leader_elected")
+	if event == other {
+		t.Fatalf("Expected a new instance")
+	}
+
+	_, err = NewEventByType("io.nats.unknown.v1.x")
+	if err == nil {
+		t.Fatalf("Expected unknown types to fail")
+	}
+}
+
 func TestSchema(t *testing.T) {
 	schema, err := Schema("io.nats.jetstream.api.v1.stream_template_names_request")
 	checkErr(t, err, "failed")
@@ -562,6 +562,8 @@ type StreamConsumerLimits struct {
 type Placement struct {
 	Cluster string   `json:"cluster" yaml:"cluster"`
 	Tags    []string `json:"tags,omitempty" yaml:"tags"`
+	// Preferred is the name of the server that should become leader when requesting a leader step down
+	Preferred string `json:"preferred,omitempty" yaml:"preferred"`
 }
 
 // StreamSourceInfo shows information about an upstream stream source.
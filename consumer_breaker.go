@@ -0,0 +1,186 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ErrBreakerOpen indicates a request was not made because the circuit breaker is open
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a Breaker
+type BreakerState int
+
+const (
+	// BreakerClosed allows all requests
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all requests until the cooldown passed
+	BreakerOpen
+	// BreakerHalfOpen allows a single trial request that closes the breaker on success or opens it again on failure
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "Closed"
+	case BreakerOpen:
+		return "Open"
+	case BreakerHalfOpen:
+		return "Half Open"
+	default:
+		return "Unknown"
+	}
+}
+
+// Breaker is a circuit breaker that opens after threshold consecutive failures, rejecting requests until cooldown
+// passed after which a single trial request is allowed. It is safe for concurrent use and can be shared between
+// consumers
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	state    BreakerState
+	failures int
+	opened   time.Time
+	trial    bool
+
+	mu sync.Mutex
+}
+
+// NewBreaker creates a circuit breaker that opens after threshold consecutive failures for cooldown
+func NewBreaker(threshold int, cooldown time.Duration) (*Breaker, error) {
+	if threshold < 1 {
+		return nil, fmt.Errorf("failure threshold must be at least 1")
+	}
+
+	if cooldown <= 0 {
+		return nil, fmt.Errorf("cooldown must be positive")
+	}
+
+	return &Breaker{threshold: threshold, cooldown: cooldown}, nil
+}
+
+// State is the current state of the breaker, an open breaker reports BreakerHalfOpen once the cooldown passed
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.currentState()
+}
+
+// Failures is the number of consecutive failures recorded
+func (b *Breaker) Failures() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.failures
+}
+
+// OpenedAt is the time the breaker last opened, zero when it never opened
+func (b *Breaker) OpenedAt() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.opened
+}
+
+func (b *Breaker) currentState() BreakerState {
+	if b.state == BreakerOpen && time.Since(b.opened) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+
+	return b.state
+}
+
+// allow determines if a request can be made, in the half open state only one trial request is allowed at a time
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case BreakerOpen:
+		return fmt.Errorf("%w, retry in %v", ErrBreakerOpen, (b.cooldown - time.Since(b.opened)).Round(time.Millisecond))
+	case BreakerHalfOpen:
+		if b.trial {
+			return ErrBreakerOpen
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+	}
+
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed request, requests that were neither successful nor
+// failed, like those cancelled by the caller, release the trial request without changing the state
+func (b *Breaker) record(success bool, failure bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+
+	switch {
+	case success:
+		b.state = BreakerClosed
+		b.failures = 0
+
+	case failure:
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+			b.state = BreakerOpen
+			b.opened = time.Now()
+		}
+	}
+}
+
+// NextMsgWithBreaker retrieves the next message like NextMsgContext while cb is closed, ErrBreakerOpen is returned
+// without making a request while it is open.
+//
+// Failures are errors other than ctx being cancelled or expiring, and status messages indicating the consumer or
+// server is not available like a deleted consumer, leadership change or server shutdown. Status messages are
+// returned to the caller as with NextMsgContext
+func (c *Consumer) NextMsgWithBreaker(ctx context.Context, cb *Breaker) (*nats.Msg, error) {
+	if cb == nil {
+		return nil, fmt.Errorf("circuit breaker is required")
+	}
+
+	err := cb.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := c.NextMsgContext(ctx)
+	if err != nil {
+		cancelled := ctx.Err() != nil
+		cb.record(false, !cancelled)
+		return nil, err
+	}
+
+	switch kind, _ := ClassifyStatusMsg(msg); kind {
+	case StatusConsumerDeleted, StatusLeadershipChange, StatusServerShutdown, StatusUnknown:
+		cb.record(false, true)
+	default:
+		cb.record(true, false)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestConsumer_NextMsgWithBreaker(t *testing.T) {
+	srv, nc, _ := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err := jsm.NewBreaker(0, time.Second)
+	if err == nil {
+		t.Fatalf("expected invalid threshold error")
+	}
+
+	cb, err := jsm.NewBreaker(2, 200*time.Millisecond)
+	checkErr(t, err, "breaker failed")
+
+	var failing atomic.Bool
+	failing.Store(true)
+
+	_, err = nc.Subscribe("FAKE.CONSUMER.INFO.ORDERS.C1", func(msg *nats.Msg) {
+		msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_info_response","stream_name":"ORDERS","name":"C1","config":{"durable_name":"C1","ack_policy":"explicit","deliver_policy":"all","replay_policy":"instant"}}`))
+	})
+	checkErr(t, err, "subscribe failed")
+	_, err = nc.Subscribe("FAKE.CONSUMER.MSG.NEXT.ORDERS.C1", func(msg *nats.Msg) {
+		reply := nats.NewMsg(msg.Reply)
+		if failing.Load() {
+			reply.Header.Set("Status", "409")
+			reply.Header.Set("Description", "Server Shutdown")
+		} else {
+			reply.Data = []byte("order 1")
+		}
+		msg.RespondMsg(reply)
+	})
+	checkErr(t, err, "subscribe failed")
+
+	mgr, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+	c, err := mgr.LoadConsumer("ORDERS", "C1")
+	checkErr(t, err, "load failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		msg, err := c.NextMsgWithBreaker(ctx, cb)
+		checkErr(t, err, "next failed")
+		if kind, _ := jsm.ClassifyStatusMsg(msg); kind != jsm.StatusServerShutdown {
+			t.Fatalf("expected server shutdown status got %s", kind)
+		}
+	}
+
+	if cb.State() != jsm.BreakerOpen || cb.Failures() != 2 {
+		t.Fatalf("expected open breaker with 2 failures got %s with %d", cb.State(), cb.Failures())
+	}
+
+	_, err = c.NextMsgWithBreaker(ctx, cb)
+	if !errors.Is(err, jsm.ErrBreakerOpen) {
+		t.Fatalf("expected open breaker error got %v", err)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if cb.State() != jsm.BreakerHalfOpen {
+		t.Fatalf("expected half open breaker got %s", cb.State())
+	}
+
+	failing.Store(false)
+
+	msg, err := c.NextMsgWithBreaker(ctx, cb)
+	checkErr(t, err, "next failed")
+	if string(msg.Data) != "order 1" {
+		t.Fatalf("invalid message %q", msg.Data)
+	}
+
+	if cb.State() != jsm.BreakerClosed || cb.Failures() != 0 {
+		t.Fatalf("expected closed breaker got %s with %d failures", cb.State(), cb.Failures())
+	}
+}
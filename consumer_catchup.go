@@ -0,0 +1,122 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// catchUpBatchSize is the number of messages requested by each pull made while catching up
+const catchUpBatchSize = 100
+
+// NewCatchUpConsumer creates a pull consumer, delivering all messages unless opts set another deliver policy, and
+// calls handler for every message it has pending up to the head of the stream at the time it was created. Once
+// caught up the consumer is returned so it can be used to follow new messages.
+//
+// Messages are acknowledged when handler succeeds, when it fails the message is negatively acknowledged and the
+// consumer is returned with the error. Progress can be tracked in handler using the pending count in the metadata
+// of each message, see ParseJSMsgMetadata()
+func (m *Manager) NewCatchUpConsumer(ctx context.Context, stream string, handler func(*nats.Msg) error, opts ...ConsumerOption) (*Consumer, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("handler is required")
+	}
+
+	cfg, err := m.buildConsumerConfiguration(DefaultConsumer, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.DeliverSubject != "" {
+		return nil, fmt.Errorf("catch up consumers must be pull consumers")
+	}
+
+	nfo, err := m.loadStreamInfoWithContext(ctx, stream, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := m.createConsumerFromConfig(ctx, stream, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := c.LatestState()
+	if err != nil {
+		return c, err
+	}
+
+	if state.NumPending == 0 {
+		return c, nil
+	}
+
+	return c, c.catchUp(ctx, nfo.State.LastSeq, state.NumPending, handler)
+}
+
+// catchUp passes messages to handler until none are pending or the message at stream sequence head was handled.
+// Pulls request at most the pending messages and expire so no pull request is left waiting once caught up
+func (c *Consumer) catchUp(ctx context.Context, head uint64, pending uint64, handler func(*nats.Msg) error) error {
+	ack := c.AckPolicy() != api.AckNone
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var herr error
+		done := false
+
+		batch := catchUpBatchSize
+		if pending > 0 && pending < uint64(batch) {
+			batch = int(pending)
+		}
+
+		_, kind, err := c.fetchBatch(ctx, api.JSApiConsumerGetNextRequest{Batch: batch, Expires: c.mgr.timeout}, func(msg *nats.Msg) bool {
+			meta, err := ParseJSMsgMetadata(msg)
+			if err != nil {
+				herr = err
+				return false
+			}
+			pending = meta.Pending()
+
+			herr = handler(msg)
+			if herr != nil {
+				if ack {
+					msg.Respond(api.AckNak)
+				}
+				return false
+			}
+
+			if ack {
+				herr = msg.Respond(api.AckAck)
+			}
+
+			done = meta.Pending() == 0 || meta.StreamSequence() >= head
+
+			return herr == nil && !done
+		})
+		switch {
+		case herr != nil:
+			return herr
+		case done:
+			return nil
+		case err != nil && kind != StatusLeadershipChange:
+			return err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestManager_NewCatchUpConsumer(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	for i := 2; i <= 150; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var seen []string
+	c, err := mgr.NewCatchUpConsumer(ctx, "ORDERS", func(msg *nats.Msg) error {
+		seen = append(seen, string(msg.Data))
+		return nil
+	}, jsm.DurableName("CACHE"))
+	checkErr(t, err, "catch up failed")
+	checkErr(t, nc.Flush(), "flush failed")
+
+	if len(seen) != 150 || seen[0] != "order 1" || seen[149] != "order 150" {
+		t.Fatalf("expected 150 messages in order got %d", len(seen))
+	}
+
+	state, err := c.State()
+	checkErr(t, err, "state failed")
+	if state.NumPending != 0 || state.NumAckPending != 0 {
+		t.Fatalf("expected consumer to be caught up: %+v", state)
+	}
+	if state.NumWaiting != 0 {
+		t.Fatalf("expected no pull requests left waiting: %+v", state)
+	}
+
+	_, err = nc.Request("ORDERS.new", []byte("order 151"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	msg, err := c.NextMsg()
+	checkErr(t, err, "next failed")
+	if string(msg.Data) != "order 151" {
+		t.Fatalf("expected the new message got %q", msg.Data)
+	}
+
+	failed := errors.New("failed")
+	c, err = mgr.NewCatchUpConsumer(ctx, "ORDERS", func(msg *nats.Msg) error { return failed })
+	if !errors.Is(err, failed) || c == nil {
+		t.Fatalf("expected handler error and consumer got %v", err)
+	}
+
+	names, err := mgr.ConsumerNames("ORDERS")
+	checkErr(t, err, "names failed")
+
+	_, err = mgr.NewCatchUpConsumer(ctx, "ORDERS", func(msg *nats.Msg) error { return nil }, jsm.DurableName("PUSH"), jsm.DeliverySubject("out"))
+	if err == nil {
+		t.Fatalf("expected push consumer error")
+	}
+
+	after, err := mgr.ConsumerNames("ORDERS")
+	checkErr(t, err, "names failed")
+	if len(after) != len(names) {
+		t.Fatalf("expected no consumer to be created for a push consumer got %v", after)
+	}
+}
@@ -0,0 +1,475 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// ConsumerOptionsFromMap creates consumer options from flat key value settings as found in environment variables
+// or configuration files. Lists are comma separated and durations use Go duration syntax.
+//
+// Supported keys are description, durable, name, ack_policy, ack_wait, max_deliver, max_ack_pending, max_waiting,
+// filter_subject, backoff, deliver_policy, start_sequence, inactive_threshold, replicas, memory_storage and headers_only
+func ConsumerOptionsFromMap(settings map[string]string) ([]ConsumerOption, error) {
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var opts []ConsumerOption
+
+	for _, k := range keys {
+		v := strings.TrimSpace(settings[k])
+
+		opt, err := consumerOptionFromSetting(k, v)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, opt)
+	}
+
+	return opts, nil
+}
+
+func consumerOptionFromSetting(key string, value string) (ConsumerOption, error) {
+	invalid := func(err error) error {
+		return fmt.Errorf("invalid value %q for consumer setting %s: %w", value, key, err)
+	}
+
+	switch key {
+	case "description":
+		return ConsumerDescription(value), nil
+
+	case "durable":
+		return DurableName(value), nil
+
+	case "name":
+		return ConsumerName(value), nil
+
+	case "ack_policy":
+		switch value {
+		case "none":
+			return AcknowledgeNone(), nil
+		case "all":
+			return AcknowledgeAll(), nil
+		case "explicit":
+			return AcknowledgeExplicit(), nil
+		default:
+			return nil, invalid(fmt.Errorf("must be one of none, all or explicit"))
+		}
+
+	case "ack_wait":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return AckWait(d), nil
+
+	case "inactive_threshold":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return InactiveThreshold(d), nil
+
+	case "max_deliver":
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return MaxDeliveryAttempts(i), nil
+
+	case "max_ack_pending":
+		i, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return MaxAckPending(uint(i)), nil
+
+	case "max_waiting":
+		i, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return MaxWaiting(uint(i)), nil
+
+	case "replicas":
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return ConsumerOverrideReplicas(i), nil
+
+	case "filter_subject":
+		subjects := splitSetting(value)
+		if len(subjects) == 0 {
+			return nil, invalid(fmt.Errorf("at least one subject is required"))
+		}
+		return FilterStreamBySubject(subjects...), nil
+
+	case "backoff":
+		var intervals []time.Duration
+		for _, p := range splitSetting(value) {
+			d, err := time.ParseDuration(p)
+			if err != nil {
+				return nil, invalid(err)
+			}
+			intervals = append(intervals, d)
+		}
+		if len(intervals) == 0 {
+			return nil, invalid(fmt.Errorf("at least one interval is required"))
+		}
+		return BackoffIntervals(intervals...), nil
+
+	case "deliver_policy":
+		switch value {
+		case "all":
+			return DeliverAllAvailable(), nil
+		case "last":
+			return StartWithLastReceived(), nil
+		case "new":
+			return StartWithNextReceived(), nil
+		case "last_per_subject":
+			return DeliverLastPerSubject(), nil
+		default:
+			return nil, invalid(fmt.Errorf("must be one of all, last, new or last_per_subject"))
+		}
+
+	case "start_sequence":
+		seq, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		return StartAtSequence(seq), nil
+
+	case "memory_storage", "headers_only":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, invalid(err)
+		}
+		if !b {
+			return func(_ *api.ConsumerConfig) error { return nil }, nil
+		}
+		if key == "memory_storage" {
+			return ConsumerOverrideMemoryStorage(), nil
+		}
+		return DeliverHeadersOnly(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown consumer setting %q", key)
+	}
+}
+
+// splitSetting splits a comma separated setting into its non empty parts
+func splitSetting(value string) []string {
+	var parts []string
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+
+	return parts
+}
+
+// ConfigKeyValues is the consumer configuration as key value pairs suitable for display, keys are the names used in
+// the JSON configuration and are in a stable order. Settings with zero values are omitted except for the ack, deliver
+// and replay policies, durations are shown in their shortest form, lists are comma separated and metadata is sorted
+func (c *Consumer) ConfigKeyValues() []struct{ Key, Value string } {
+	cfg := c.Configuration()
+
+	var kvs []struct{ Key, Value string }
+	add := func(key string, value string) {
+		if value != "" {
+			kvs = append(kvs, struct{ Key, Value string }{key, value})
+		}
+	}
+	addInt := func(key string, value int64) {
+		if value != 0 {
+			add(key, strconv.FormatInt(value, 10))
+		}
+	}
+	addBool := func(key string, value bool) {
+		if value {
+			add(key, "true")
+		}
+	}
+	addTime := func(key string, value *time.Time) {
+		if value != nil && !value.IsZero() {
+			add(key, value.UTC().Format(time.RFC3339))
+		}
+	}
+
+	add("name", cfg.Name)
+	add("durable_name", cfg.Durable)
+	add("description", cfg.Description)
+	add("ack_policy", cfg.AckPolicy.String())
+	add("ack_wait", shortDuration(cfg.AckWait))
+	add("deliver_policy", cfg.DeliverPolicy.String())
+	addInt("opt_start_seq", int64(cfg.OptStartSeq))
+	addTime("opt_start_time", cfg.OptStartTime)
+	add("deliver_subject", cfg.DeliverSubject)
+	add("deliver_group", cfg.DeliverGroup)
+	add("filter_subject", cfg.FilterSubject)
+	add("filter_subjects", strings.Join(cfg.FilterSubjects, ", "))
+	addBool("flow_control", cfg.FlowControl)
+	add("idle_heartbeat", shortDuration(cfg.Heartbeat))
+	addInt("max_ack_pending", int64(cfg.MaxAckPending))
+	if cfg.MaxDeliver < 0 {
+		add("max_deliver", "unlimited")
+	} else {
+		addInt("max_deliver", int64(cfg.MaxDeliver))
+	}
+	backoff := make([]string, len(cfg.BackOff))
+	for i, d := range cfg.BackOff {
+		backoff[i] = shortDuration(d)
+	}
+	add("backoff", strings.Join(backoff, ", "))
+	addInt("max_waiting", int64(cfg.MaxWaiting))
+	addInt("rate_limit_bps", int64(cfg.RateLimit))
+	add("replay_policy", cfg.ReplayPolicy.String())
+	add("sample_freq", cfg.SampleFrequency)
+	addBool("headers_only", cfg.HeadersOnly)
+	addInt("max_batch", int64(cfg.MaxRequestBatch))
+	add("max_expires", shortDuration(cfg.MaxRequestExpires))
+	addInt("max_bytes", int64(cfg.MaxRequestMaxBytes))
+	add("inactive_threshold", shortDuration(cfg.InactiveThreshold))
+	addInt("num_replicas", int64(cfg.Replicas))
+	addBool("mem_storage", cfg.MemoryStorage)
+	addTime("pause_until", cfg.PauseUntil)
+	add("priority_groups", strings.Join(cfg.PriorityGroups, ", "))
+	if cfg.PriorityPolicy != api.PriorityNone {
+		add("priority_policy", cfg.PriorityPolicy.String())
+	}
+	addBool("direct", cfg.Direct)
+
+	keys := make([]string, 0, len(cfg.Metadata))
+	for k := range cfg.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	meta := make([]string, len(keys))
+	for i, k := range keys {
+		meta[i] = fmt.Sprintf("%s=%s", k, cfg.Metadata[k])
+	}
+	add("metadata", strings.Join(meta, ", "))
+
+	return kvs
+}
+
+// shortDuration formats d without trailing zero units, 1m rather than 1m0s, and returns an empty string for 0
+func shortDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return s
+}
+
+// ConfigHCL renders the consumer configuration as a jetstream_consumer resource for the NATS Terraform provider, for
+// example to manage an existing consumer in Terraform after importing it. Durations are in whole seconds as the
+// provider expects, settings the provider does not support like priority groups and metadata keys set by the server
+// are not included.
+//
+// Only durable consumers can be managed by the provider
+func (c *Consumer) ConfigHCL() (string, error) {
+	cfg := c.Configuration()
+
+	if cfg.Durable == "" {
+		return "", fmt.Errorf("consumer %s > %s is not durable", c.stream, c.name)
+	}
+
+	var attrs [][2]string
+	var err error
+
+	add := func(key string, value string) {
+		attrs = append(attrs, [2]string{key, value})
+	}
+	addString := func(key string, value string) {
+		if value != "" {
+			add(key, hclString(value))
+		}
+	}
+	addInt := func(key string, value int64) {
+		if value != 0 {
+			add(key, strconv.FormatInt(value, 10))
+		}
+	}
+	addBool := func(key string, value bool) {
+		if value {
+			add(key, "true")
+		}
+	}
+	seconds := func(key string, d time.Duration) string {
+		if d%time.Second != 0 && err == nil {
+			err = fmt.Errorf("%s of %v can not be represented in whole seconds", key, d)
+		}
+		return strconv.FormatInt(int64(d/time.Second), 10)
+	}
+	addSeconds := func(key string, d time.Duration) {
+		if d != 0 {
+			add(key, seconds(key, d))
+		}
+	}
+
+	addString("stream_id", fmt.Sprintf("JETSTREAM_STREAM_%s", c.stream))
+	addString("durable_name", cfg.Durable)
+	addString("description", cfg.Description)
+	addString("delivery_subject", cfg.DeliverSubject)
+	addString("delivery_group", cfg.DeliverGroup)
+
+	switch cfg.DeliverPolicy {
+	case api.DeliverAll:
+		addBool("deliver_all", true)
+	case api.DeliverLast:
+		addBool("deliver_last", true)
+	case api.DeliverLastPerSubject:
+		addBool("deliver_last_per_subject", true)
+	case api.DeliverNew:
+		addBool("deliver_new", true)
+	case api.DeliverByStartSequence:
+		addInt("stream_sequence", int64(cfg.OptStartSeq))
+	case api.DeliverByStartTime:
+		if cfg.OptStartTime != nil {
+			addString("start_time", cfg.OptStartTime.UTC().Format(time.RFC3339))
+		}
+	}
+
+	addString("ack_policy", strings.ToLower(cfg.AckPolicy.String()))
+	addSeconds("ack_wait", cfg.AckWait)
+	addInt("max_delivery", int64(cfg.MaxDeliver))
+
+	filters := consumerFilterSubjects(&cfg)
+	switch len(filters) {
+	case 0:
+	case 1:
+		addString("filter_subject", filters[0])
+	default:
+		quoted := make([]string, len(filters))
+		for i, f := range filters {
+			quoted[i] = hclString(f)
+		}
+		add("filter_subjects", fmt.Sprintf("[%s]", strings.Join(quoted, ", ")))
+	}
+
+	addString("replay_policy", strings.ToLower(cfg.ReplayPolicy.String()))
+	if cfg.SampleFrequency != "" {
+		freq, perr := strconv.Atoi(strings.TrimSuffix(cfg.SampleFrequency, "%"))
+		if perr != nil {
+			return "", fmt.Errorf("invalid sample frequency %q: %w", cfg.SampleFrequency, perr)
+		}
+		addInt("sample_freq", int64(freq))
+	}
+	addInt("ratelimit", int64(cfg.RateLimit))
+	addInt("max_ack_pending", int64(cfg.MaxAckPending))
+	addSeconds("heartbeat", cfg.Heartbeat)
+	addBool("flow_control", cfg.FlowControl)
+	addInt("max_waiting", int64(cfg.MaxWaiting))
+	addBool("headers_only", cfg.HeadersOnly)
+	addInt("max_batch", int64(cfg.MaxRequestBatch))
+	addSeconds("max_expires", cfg.MaxRequestExpires)
+	addInt("max_bytes", int64(cfg.MaxRequestMaxBytes))
+	addSeconds("inactive_threshold", cfg.InactiveThreshold)
+	addInt("replicas", int64(cfg.Replicas))
+	addBool("memory", cfg.MemoryStorage)
+
+	if len(cfg.BackOff) > 0 {
+		backoff := make([]string, len(cfg.BackOff))
+		for i, d := range cfg.BackOff {
+			backoff[i] = seconds("backoff", d)
+		}
+		add("backoff", fmt.Sprintf("[%s]", strings.Join(backoff, ", ")))
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	width := 0
+	for _, attr := range attrs {
+		if len(attr[0]) > width {
+			width = len(attr[0])
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "resource \"jetstream_consumer\" %s {\n", hclString(hclIdentifier(c.stream+"_"+c.name)))
+	for _, attr := range attrs {
+		fmt.Fprintf(&b, "  %-*s = %s\n", width, attr[0], attr[1])
+	}
+
+	// keys set by the server are reserved and can not be managed using the provider
+	meta := withoutServerMetadata(cfg.Metadata)
+	if len(meta) > 0 {
+		keys := make([]string, 0, len(meta))
+		width = 0
+		for k := range meta {
+			keys = append(keys, k)
+			if len(hclString(k)) > width {
+				width = len(hclString(k))
+			}
+		}
+		sort.Strings(keys)
+
+		b.WriteString("\n  metadata = {\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "    %-*s = %s\n", width, hclString(k), hclString(meta[k]))
+		}
+		b.WriteString("  }\n")
+	}
+
+	b.WriteString("}\n")
+
+	return b.String(), nil
+}
+
+// hclString quotes s as a HCL string, escaping template sequences so the value is used literally
+func hclString(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	q = strings.ReplaceAll(q, "%{", "%%{")
+
+	return q
+}
+
+// hclIdentifier makes s a valid HCL identifier by replacing unsupported characters with underscores
+func hclIdentifier(s string) string {
+	id := []rune(s)
+	for i, r := range id {
+		valid := r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !valid || (i == 0 && (r == '-' || (r >= '0' && r <= '9'))) {
+			id[i] = '_'
+		}
+	}
+
+	return string(id)
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestConsumerOptionsFromMap(t *testing.T) {
+	opts, err := jsm.ConsumerOptionsFromMap(map[string]string{
+		"durable":        "ORDERS",
+		"ack_wait":       "1m",
+		"max_deliver":    "5",
+		"filter_subject": "ORDERS.new, ORDERS.shipped",
+		"backoff":        "1s,5s, 10s",
+		"deliver_policy": "last_per_subject",
+		"memory_storage": "true",
+		"headers_only":   "false",
+	})
+	checkErr(t, err, "parse failed")
+
+	cfg, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, opts...)
+	checkErr(t, err, "configuration failed")
+
+	if cfg.Durable != "ORDERS" || cfg.AckWait != time.Minute || cfg.MaxDeliver != 5 || cfg.DeliverPolicy != api.DeliverLastPerSubject {
+		t.Fatalf("invalid configuration: %+v", cfg)
+	}
+	if !cfg.MemoryStorage || cfg.HeadersOnly {
+		t.Fatalf("invalid storage or headers settings: %+v", cfg)
+	}
+	if !cmp.Equal(cfg.FilterSubjects, []string{"ORDERS.new", "ORDERS.shipped"}) {
+		t.Fatalf("invalid filter subjects: %v", cfg.FilterSubjects)
+	}
+	if !cmp.Equal(cfg.BackOff, []time.Duration{time.Second, 5 * time.Second, 10 * time.Second}) {
+		t.Fatalf("invalid backoff: %v", cfg.BackOff)
+	}
+
+	_, err = jsm.ConsumerOptionsFromMap(map[string]string{"ack_wiat": "1m"})
+	if err == nil || err.Error() != `unknown consumer setting "ack_wiat"` {
+		t.Fatalf("expected unknown setting error got %v", err)
+	}
+
+	_, err = jsm.ConsumerOptionsFromMap(map[string]string{"ack_wait": "1 minute"})
+	if err == nil || !strings.HasPrefix(err.Error(), `invalid value "1 minute" for consumer setting ack_wait: `) {
+		t.Fatalf("expected invalid value error got %v", err)
+	}
+
+	_, err = jsm.ConsumerOptionsFromMap(map[string]string{"deliver_policy": "first"})
+	if err == nil {
+		t.Fatalf("expected invalid deliver policy error")
+	}
+}
+
+func TestConsumer_ConfigKeyValues(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	consumer, err := mgr.NewConsumer("ORDERS", jsm.DurableName("KV"), jsm.FilterStreamBySubject("ORDERS.new", "ORDERS.shipped"), jsm.MaxDeliveryAttempts(5), jsm.BackoffIntervals(time.Second, 90*time.Second), jsm.ConsumerMetadata(map[string]string{"team": "orders", "env": "prod"}))
+	checkErr(t, err, "create failed")
+
+	var keys []string
+	kvs := map[string]string{}
+	for _, kv := range consumer.ConfigKeyValues() {
+		keys = append(keys, kv.Key)
+		kvs[kv.Key] = kv.Value
+	}
+
+	expected := []string{"name", "durable_name", "ack_policy", "ack_wait", "deliver_policy", "filter_subjects", "max_ack_pending", "max_deliver", "backoff", "max_waiting", "replay_policy", "metadata"}
+	if !cmp.Equal(keys, expected) {
+		t.Fatalf("invalid keys: %s", cmp.Diff(expected, keys))
+	}
+
+	if kvs["ack_wait"] != "1s" || kvs["backoff"] != "1s, 1m30s" || kvs["ack_policy"] != "Explicit" || kvs["deliver_policy"] != "All" {
+		t.Fatalf("invalid values: %v", kvs)
+	}
+	if kvs["filter_subjects"] != "ORDERS.new, ORDERS.shipped" || kvs["metadata"] != "env=prod, team=orders" {
+		t.Fatalf("invalid values: %v", kvs)
+	}
+}
+
+func TestConsumer_ConfigHCL(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	rmgr, err := jsm.New(nc, jsm.WithReservedConsumerMetadata())
+	checkErr(t, err, "manager failed")
+	consumer, err := rmgr.NewConsumer("ORDERS", jsm.DurableName("HCL"), jsm.ConsumerDescription("orders ${env}"), jsm.FilterStreamBySubject("ORDERS.new", "ORDERS.shipped"), jsm.MaxDeliveryAttempts(5), jsm.BackoffIntervals(time.Second, 90*time.Second), jsm.ConsumerMetadata(map[string]string{"team": "orders", "env": "prod"}), jsm.AddConsumerMetadata(map[string]string{"_nats.level": "1"}))
+	checkErr(t, err, "create failed")
+	// the test server does not set metadata so a key set by the server is simulated
+	if consumer.Metadata()["_nats.level"] != "1" {
+		t.Fatalf("expected server metadata: %v", consumer.Metadata())
+	}
+
+	hcl, err := consumer.ConfigHCL()
+	checkErr(t, err, "hcl failed")
+
+	expected := `resource "jetstream_consumer" "ORDERS_HCL" {
+  stream_id       = "JETSTREAM_STREAM_ORDERS"
+  durable_name    = "HCL"
+  description     = "orders $${env}"
+  deliver_all     = true
+  ack_policy      = "explicit"
+  ack_wait        = 1
+  max_delivery    = 5
+  filter_subjects = ["ORDERS.new", "ORDERS.shipped"]
+  replay_policy   = "instant"
+  max_ack_pending = 1000
+  max_waiting     = 512
+  backoff         = [1, 90]
+
+  metadata = {
+    "env"  = "prod"
+    "team" = "orders"
+  }
+}
+`
+	if hcl != expected {
+		t.Fatalf("invalid hcl: %s", cmp.Diff(expected, hcl))
+	}
+
+	consumer, err = mgr.NewConsumer("ORDERS", jsm.AckWait(1500*time.Millisecond))
+	checkErr(t, err, "create failed")
+	_, err = consumer.ConfigHCL()
+	if err == nil {
+		t.Fatalf("expected ephemeral consumer error")
+	}
+
+	consumer, err = mgr.NewConsumer("ORDERS", jsm.DurableName("PARTIAL"), jsm.AckWait(1500*time.Millisecond))
+	checkErr(t, err, "create failed")
+	_, err = consumer.ConfigHCL()
+	if err == nil || err.Error() != "ack_wait of 1.5s can not be represented in whole seconds" {
+		t.Fatalf("expected whole seconds error got %v", err)
+	}
+}
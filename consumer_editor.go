@@ -0,0 +1,139 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// ConsumerEditor edits the configuration of an existing consumer, changes are made using its methods or any
+// ConsumerOption passed to With() and are saved using Apply(). The first error encountered is kept and returned by
+// Apply(), further changes are ignored once an error occurred
+type ConsumerEditor struct {
+	consumer *Consumer
+	original api.ConsumerConfig
+	cfg      api.ConsumerConfig
+	err      error
+}
+
+// Editor creates a ConsumerEditor starting from the current configuration of the consumer
+func (c *Consumer) Editor() *ConsumerEditor {
+	cfg := c.Configuration()
+
+	return &ConsumerEditor{
+		consumer: c,
+		original: copyConsumerConfig(cfg),
+		cfg:      copyConsumerConfig(cfg),
+	}
+}
+
+// With applies opts to the configuration being edited, settings depending on the final configuration like
+// InactiveThresholdMultiple() are resolved as when updating the consumer
+func (e *ConsumerEditor) With(opts ...ConsumerOption) *ConsumerEditor {
+	if e.err != nil {
+		return e
+	}
+
+	cfg, err := e.consumer.mgr.buildConsumerConfiguration(e.cfg, opts...)
+	if err != nil {
+		e.err = err
+		return e
+	}
+
+	e.cfg = *cfg
+
+	return e
+}
+
+// Description sets the consumer description, see ConsumerDescription()
+func (e *ConsumerEditor) Description(d string) *ConsumerEditor {
+	return e.With(ConsumerDescription(d))
+}
+
+// AckWait sets the time to wait for acknowledgements, see AckWait()
+func (e *ConsumerEditor) AckWait(t time.Duration) *ConsumerEditor {
+	return e.With(AckWait(t))
+}
+
+// MaxDeliveryAttempts sets the maximum number of deliveries of a message, see MaxDeliveryAttempts()
+func (e *ConsumerEditor) MaxDeliveryAttempts(n int) *ConsumerEditor {
+	return e.With(MaxDeliveryAttempts(n))
+}
+
+// MaxAckPending sets the maximum number of messages awaiting acknowledgement, see MaxAckPending()
+func (e *ConsumerEditor) MaxAckPending(pending uint) *ConsumerEditor {
+	return e.With(MaxAckPending(pending))
+}
+
+// MaxWaiting sets the maximum number of outstanding pull requests, see MaxWaiting()
+func (e *ConsumerEditor) MaxWaiting(pulls uint) *ConsumerEditor {
+	return e.With(MaxWaiting(pulls))
+}
+
+// BackoffIntervals sets the redelivery backoff intervals, see BackoffIntervals()
+func (e *ConsumerEditor) BackoffIntervals(i ...time.Duration) *ConsumerEditor {
+	return e.With(BackoffIntervals(i...))
+}
+
+// FilterSubjects sets the subjects the consumer delivers, see FilterStreamBySubject()
+func (e *ConsumerEditor) FilterSubjects(s ...string) *ConsumerEditor {
+	return e.With(FilterStreamBySubject(s...))
+}
+
+// Metadata replaces the consumer metadata, see ConsumerMetadata()
+func (e *ConsumerEditor) Metadata(meta map[string]string) *ConsumerEditor {
+	return e.With(ConsumerMetadata(meta))
+}
+
+// InactiveThreshold sets the time after which an inactive consumer is removed, see InactiveThreshold()
+func (e *ConsumerEditor) InactiveThreshold(t time.Duration) *ConsumerEditor {
+	return e.With(InactiveThreshold(t))
+}
+
+// Changes are the names of the api.ConsumerConfig fields changed by the editor
+func (e *ConsumerEditor) Changes() []string {
+	var changed []string
+	for _, d := range consumerConfigFieldDifferences(normalizedConsumerConfig(e.original), normalizedConsumerConfig(e.cfg), false) {
+		changed = append(changed, d.field)
+	}
+
+	return changed
+}
+
+// Apply updates the consumer setting only the fields changed by the editor on its current configuration using
+// UpdateConfiguration(), no request is made when nothing changed
+func (e *ConsumerEditor) Apply() error {
+	if e.err != nil {
+		return e.err
+	}
+
+	changed := e.Changes()
+	if len(changed) == 0 {
+		return nil
+	}
+
+	edited := reflect.ValueOf(normalizedConsumerConfig(e.cfg))
+
+	return e.consumer.UpdateConfiguration(func(o *api.ConsumerConfig) error {
+		ov := reflect.ValueOf(o).Elem()
+		for _, field := range changed {
+			ov.FieldByName(field).Set(edited.FieldByName(field))
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestConsumer_Editor(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("EDIT"), jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "create failed")
+
+	editor := c.Editor().Description("edited").AckWait(time.Minute).FilterSubjects("ORDERS.new")
+	if !cmp.Equal(editor.Changes(), []string{"Description", "AckWait"}) {
+		t.Fatalf("invalid changes: %v", editor.Changes())
+	}
+
+	if c.Description() != "" {
+		t.Fatalf("expected the consumer to be unchanged before apply")
+	}
+
+	checkErr(t, editor.Apply(), "apply failed")
+	if c.Description() != "edited" || c.AckWait() != time.Minute || c.FilterSubject() != "ORDERS.new" {
+		t.Fatalf("invalid configuration: %+v", c.Configuration())
+	}
+
+	checkErr(t, c.Editor().Apply(), "apply failed")
+
+	err = c.Editor().MaxDeliveryAttempts(0).Description("ignored").Apply()
+	if err == nil {
+		t.Fatalf("expected invalid max deliver error")
+	}
+	if c.Description() != "edited" {
+		t.Fatalf("expected no changes after an error")
+	}
+
+	// options resolved once the configuration is built work the same as when updating the consumer
+	checkErr(t, c.Editor().With(jsm.InactiveThresholdMultiple(2)).Apply(), "apply failed")
+	if c.InactiveThreshold() != 2*time.Minute {
+		t.Fatalf("expected an inactive threshold of 2 minutes got %v", c.InactiveThreshold())
+	}
+}
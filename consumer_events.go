@@ -0,0 +1,281 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+	jsadvisory "github.com/nats-io/jsm.go/api/jetstream/advisory"
+	jsmetric "github.com/nats-io/jsm.go/api/jetstream/metric"
+)
+
+const (
+	// minAckWaitSamples is the minimum number of ack samples needed to suggest an ack wait
+	minAckWaitSamples = 10
+	// ackWaitSafetyFactor is applied to the observed p99 ack latency
+	ackWaitSafetyFactor = 1.5
+)
+
+// SuggestAckWait observes acknowledgement samples for the sample period and suggests an AckWait based
+// on the 99th percentile of observed ack latencies multiplied by a safety factor of 1.5.
+//
+// The consumer must have ack sampling enabled, see SamplePercent(), and at least 10 samples need to be
+// received during the sample period or before ctx is cancelled
+func (c *Consumer) SuggestAckWait(ctx context.Context, sample time.Duration) (time.Duration, error) {
+	if !c.IsSampled() {
+		return 0, fmt.Errorf("consumer %s > %s does not have ack sampling enabled", c.stream, c.name)
+	}
+
+	msgs := make(chan *nats.Msg, 1000)
+	sub, err := c.mgr.nc.ChanSubscribe(c.AckSampleSubject(), msgs)
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsubscribe()
+
+	timer := time.NewTimer(sample)
+	defer timer.Stop()
+
+	var delays []time.Duration
+
+	func() {
+		for {
+			select {
+			case msg := <-msgs:
+				_, event, err := api.ParseMessage(msg.Data)
+				if err != nil {
+					continue
+				}
+
+				ack, ok := event.(*jsmetric.ConsumerAckMetricV1)
+				if !ok {
+					continue
+				}
+
+				delays = append(delays, time.Duration(ack.Delay))
+
+			case <-timer.C:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if len(delays) < minAckWaitSamples {
+		return 0, fmt.Errorf("insufficient data to suggest an ack wait, received %d ack samples but %d are required", len(delays), minAckWaitSamples)
+	}
+
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+
+	p99 := delays[int(math.Ceil(0.99*float64(len(delays))))-1]
+
+	suggested := time.Duration(float64(p99) * ackWaitSafetyFactor).Round(time.Millisecond)
+	if suggested < time.Millisecond {
+		suggested = time.Millisecond
+	}
+
+	return suggested, nil
+}
+
+// ReconcileLoop compares the live consumer configuration to desired every interval, calling onDrift with a description
+// of the differences when they diverge. Only settings with a non zero value in desired are compared so server
+// assigned defaults do not cause drift, neither do metadata keys set by the server. onDrift decides how to
+// remediate, for example by calling UpdateConfiguration, and any error it returns stops the loop. Transient errors
+// loading the consumer state are retried on the next interval.
+//
+// The loop runs until ctx is cancelled when it returns nil
+func (c *Consumer) ReconcileLoop(ctx context.Context, desired api.ConsumerConfig, interval time.Duration, onDrift func(diff []string) error) error {
+	if interval <= 0 {
+		return fmt.Errorf("reconcile interval must be positive")
+	}
+
+	if onDrift == nil {
+		return fmt.Errorf("drift callback is required")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		nfo, err := c.stateWithContext(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil && !isRetryableError(err):
+			return err
+		case err == nil:
+			diff := consumerConfigDifferences(comparableConsumerConfig(nfo.Config), comparableConsumerConfig(desired), true)
+			if len(diff) > 0 {
+				err = onDrift(diff)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// OnLeaderChange calls cb whenever the consumer elects a new RAFT leader until ctx is cancelled, oldLeader is the
+// leader before the election and is empty when it was not known. The advisories are received using nc, or the
+// connection of the manager when nil, and cb is called from the subscription handler
+func (c *Consumer) OnLeaderChange(ctx context.Context, nc *nats.Conn, cb func(oldLeader string, newLeader string)) error {
+	if cb == nil {
+		return fmt.Errorf("leader change callback is required")
+	}
+
+	if nc == nil {
+		nc = c.mgr.nc
+	}
+
+	var leader string
+	nfo, err := c.stateWithContext(ctx)
+	if err == nil && nfo.Cluster != nil {
+		leader = nfo.Cluster.Leader
+	}
+
+	sub, err := nc.Subscribe(fmt.Sprintf("%s.%s.%s", api.JSAdvisoryConsumerLeaderElectedPre, c.stream, c.name), func(msg *nats.Msg) {
+		_, event, err := api.ParseMessage(msg.Data)
+		if err != nil {
+			return
+		}
+
+		elected, ok := event.(*jsadvisory.JSConsumerLeaderElectedV1)
+		if !ok || elected.Leader == leader {
+			return
+		}
+
+		old := leader
+		leader = elected.Leader
+		cb(old, elected.Leader)
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		sub.Unsubscribe()
+	}()
+
+	return nil
+}
+
+// WatchRedeliveryStorm calls cb with the number of redeliveries seen during the last window whenever it exceeds
+// threshold until ctx is cancelled. After cb is called it is not called again until another window has passed.
+//
+// Redeliveries are counted from negative acknowledgement and maximum delivery advisories and, when the consumer
+// has ack sampling enabled, from acknowledgements of messages that were delivered more than once. Messages
+// redelivered after the ack wait expired are only seen in ack samples. The advisories are received using nc, or the
+// connection of the manager when nil, and cb is called from a background goroutine
+func (c *Consumer) WatchRedeliveryStorm(ctx context.Context, nc *nats.Conn, threshold int, window time.Duration, cb func(count int)) error {
+	if threshold < 1 {
+		return fmt.Errorf("threshold must be at least 1")
+	}
+
+	if window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+
+	if cb == nil {
+		return fmt.Errorf("redelivery storm callback is required")
+	}
+
+	if nc == nil {
+		nc = c.mgr.nc
+	}
+
+	subjects := []string{
+		fmt.Sprintf("%s.%s.%s", api.JSAdvisoryConsumerMsgNakedPre, c.stream, c.name),
+		fmt.Sprintf("%s.%s.%s", api.JSAdvisoryConsumerMaxDeliveryExceedPre, c.stream, c.name),
+	}
+	if c.IsSampled() {
+		subjects = append(subjects, c.AckSampleSubject())
+	}
+
+	msgs := make(chan *nats.Msg, 1000)
+	var subs []*nats.Subscription
+	unsubscribe := func() {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+	}
+
+	for _, subject := range subjects {
+		sub, err := nc.ChanSubscribe(subject, msgs)
+		if err != nil {
+			unsubscribe()
+			return err
+		}
+		subs = append(subs, sub)
+	}
+
+	go func() {
+		defer unsubscribe()
+
+		var seen []time.Time
+		var fired time.Time
+
+		for {
+			select {
+			case msg := <-msgs:
+				_, event, err := api.ParseMessage(msg.Data)
+				if err != nil {
+					continue
+				}
+
+				switch e := event.(type) {
+				case *jsadvisory.JSConsumerDeliveryNakAdvisoryV1, *jsadvisory.ConsumerDeliveryExceededAdvisoryV1:
+				case *jsmetric.ConsumerAckMetricV1:
+					if e.Deliveries < 2 {
+						continue
+					}
+				default:
+					continue
+				}
+
+				now := time.Now()
+				seen = append(seen, now)
+
+				cutoff := now.Add(-window)
+				for len(seen) > 0 && seen[0].Before(cutoff) {
+					seen = seen[1:]
+				}
+
+				if len(seen) > threshold && now.Sub(fired) >= window {
+					fired = now
+					cb(len(seen))
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
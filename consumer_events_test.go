@@ -0,0 +1,209 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestConsumer_SuggestAckWait(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	unsampled, err := mgr.NewConsumer("ORDERS", jsm.DurableName("UNSAMPLED"))
+	checkErr(t, err, "create failed")
+	_, err = unsampled.SuggestAckWait(context.Background(), time.Millisecond)
+	if err == nil {
+		t.Fatalf("expected an error for unsampled consumers")
+	}
+
+	for i := 0; i < 20; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	sampled, err := mgr.NewConsumerFromDefault("ORDERS", jsm.SampledDefaultConsumer, jsm.DurableName("SAMPLED"))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type result struct {
+		wait time.Duration
+		err  error
+	}
+	res := make(chan result, 1)
+
+	go func() {
+		wait, err := sampled.SuggestAckWait(ctx, 2*time.Second)
+		res <- result{wait, err}
+	}()
+
+	// give the sampler time to subscribe
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < 21; i++ {
+		msg, err := sampled.NextMsg()
+		checkErr(t, err, "next failed")
+		time.Sleep(10 * time.Millisecond)
+		checkErr(t, msg.Ack(), "ack failed")
+	}
+
+	r := <-res
+	checkErr(t, r.err, "suggest failed")
+	if r.wait < 10*time.Millisecond || r.wait > time.Second {
+		t.Fatalf("unexpected suggested ack wait %v", r.wait)
+	}
+}
+
+func TestConsumer_ReconcileLoop(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.ConsumerDescription("old"))
+	checkErr(t, err, "create failed")
+
+	desired := api.ConsumerConfig{Durable: "C1", Description: "new", AckPolicy: api.AckExplicit}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	var drifts [][]string
+	err = c.ReconcileLoop(ctx, desired, 10*time.Millisecond, func(diff []string) error {
+		drifts = append(drifts, diff)
+		if len(drifts) == 1 {
+			return c.UpdateConfiguration(jsm.ConsumerDescription("new"))
+		}
+
+		return fmt.Errorf("unexpected drift")
+	})
+
+	if len(drifts) != 1 {
+		t.Fatalf("expected 1 drift got %v: %v", drifts, err)
+	}
+	if len(drifts[0]) != 1 || drifts[0][0] != "Description: old != new" {
+		t.Fatalf("invalid drift: %v", drifts[0])
+	}
+	checkErr(t, err, "reconcile failed")
+
+	// a consumer created from desired does not drift when the server stores a single filter in FilterSubject and
+	// adds its own metadata keys, the server keys are simulated as the test server does not set any
+	desired = api.ConsumerConfig{Durable: "C2", AckPolicy: api.AckExplicit, FilterSubjects: []string{"ORDERS.new"}, Metadata: map[string]string{"team": "orders"}}
+	rmgr, err := jsm.New(nc, jsm.WithReservedConsumerMetadata())
+	checkErr(t, err, "manager failed")
+	c, err = rmgr.NewConsumer("ORDERS", jsm.FromConfig(desired), jsm.AddConsumerMetadata(map[string]string{"_nats.level": "1"}))
+	checkErr(t, err, "create failed")
+	if c.FilterSubject() != "ORDERS.new" || c.Metadata()["_nats.level"] != "1" {
+		t.Fatalf("expected a single filter subject and server metadata: %#v", c.Configuration())
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err = c.ReconcileLoop(ctx, desired, 10*time.Millisecond, func(diff []string) error {
+		return fmt.Errorf("unexpected drift: %v", diff)
+	})
+	checkErr(t, err, "reconcile failed")
+}
+
+func TestConsumer_OnLeaderChange(t *testing.T) {
+	withJSCluster(t, func(t *testing.T, _ []*server.Server, nc *nats.Conn, mgr *jsm.Manager) {
+		_, err := mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.Replicas(3), jsm.MemoryStorage())
+		checkErr(t, err, "create failed")
+
+		c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+		checkErr(t, err, "create failed")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		checkErr(t, c.WaitForAssignment(ctx), "wait failed")
+		nfo, err := c.State()
+		checkErr(t, err, "state failed")
+		if nfo.Cluster == nil || nfo.Cluster.Leader == "" {
+			t.Fatalf("expected a leader")
+		}
+
+		changes := make(chan [2]string, 10)
+		err = c.OnLeaderChange(ctx, nil, func(oldLeader string, newLeader string) {
+			changes <- [2]string{oldLeader, newLeader}
+		})
+		checkErr(t, err, "subscribe failed")
+
+		checkErr(t, c.LeaderStepDown(), "step down failed")
+
+		select {
+		case change := <-changes:
+			if change[0] != nfo.Cluster.Leader || change[1] == "" || change[1] == change[0] {
+				t.Fatalf("invalid leader change %v from %s", change, nfo.Cluster.Leader)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no leader change received")
+		}
+	})
+}
+
+func TestConsumer_WatchRedeliveryStorm(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	err = c.WatchRedeliveryStorm(context.Background(), nil, 0, time.Second, func(int) {})
+	if err == nil {
+		t.Fatalf("expected invalid thresholds to fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	counts := make(chan int, 10)
+	err = c.WatchRedeliveryStorm(ctx, nil, 3, time.Minute, func(count int) { counts <- count })
+	checkErr(t, err, "watch failed")
+
+	for i := 0; i < 6; i++ {
+		msg, err := c.NextMsg()
+		checkErr(t, err, "next failed")
+		_, err = nc.Request(msg.Reply, api.AckNak, time.Second)
+		checkErr(t, err, "nak failed")
+	}
+
+	select {
+	case count := <-counts:
+		if count != 4 {
+			t.Fatalf("expected a storm of 4 redeliveries got %d", count)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected a redelivery storm")
+	}
+
+	select {
+	case count := <-counts:
+		t.Fatalf("expected the storm to be reported once got %d", count)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
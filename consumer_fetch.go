@@ -0,0 +1,183 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// PullOpt configures a pull request made using Fetch
+type PullOpt func(req *api.JSApiConsumerGetNextRequest) error
+
+// PullExpires sets how long the server keeps the pull request active waiting for the batch to fill, the manager
+// timeout or the deadline of the context is used by default
+func PullExpires(d time.Duration) PullOpt {
+	return func(req *api.JSApiConsumerGetNextRequest) error {
+		if d <= 0 {
+			return fmt.Errorf("pull expiry must be positive")
+		}
+
+		req.Expires = d
+		return nil
+	}
+}
+
+// PullMaxBytes limits the total size of the messages delivered for the pull request
+func PullMaxBytes(n int) PullOpt {
+	return func(req *api.JSApiConsumerGetNextRequest) error {
+		if n < 1 {
+			return fmt.Errorf("pull max bytes must be at least 1")
+		}
+
+		req.MaxBytes = n
+		return nil
+	}
+}
+
+// PullNoWait completes the pull request as soon as no more messages are available rather than waiting for the batch
+// to fill
+func PullNoWait() PullOpt {
+	return func(req *api.JSApiConsumerGetNextRequest) error {
+		req.NoWait = true
+		return nil
+	}
+}
+
+// PullHeartbeat requests idle heartbeats every d while the pull request is active, the pull fails with
+// ErrMissedHeartbeats when 2 heartbeats are missed
+func PullHeartbeat(d time.Duration) PullOpt {
+	return func(req *api.JSApiConsumerGetNextRequest) error {
+		if d <= 0 {
+			return fmt.Errorf("pull heartbeat must be positive")
+		}
+
+		req.Heartbeat = d
+		return nil
+	}
+}
+
+// PullGroup makes the pull request against a priority group of the consumer, the first group configured on the
+// consumer is used by default
+func PullGroup(group string) PullOpt {
+	return func(req *api.JSApiConsumerGetNextRequest) error {
+		req.Group = group
+		return nil
+	}
+}
+
+// MessageBatch is a pull request made using Fetch
+type MessageBatch interface {
+	// Messages delivers the messages received for the pull request and is closed once the request completed
+	Messages() <-chan *nats.Msg
+	// Error is the reason the pull request failed, it is nil for requests that completed normally and should only
+	// be checked after the Messages() channel is closed
+	Error() error
+}
+
+type messageBatch struct {
+	msgs chan *nats.Msg
+	err  error
+	mu   sync.Mutex
+}
+
+func (b *messageBatch) Messages() <-chan *nats.Msg { return b.msgs }
+
+func (b *messageBatch) Error() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.err
+}
+
+// ErrFetchNotRead indicates messages received by Fetch were not read from the batch before the pull request expired
+var ErrFetchNotRead = errors.New("fetched messages were not read before the pull request expired")
+
+// maxFetchBuffer is the most messages Fetch buffers before delivery waits for them to be read
+const maxFetchBuffer = 1024
+
+// Fetch makes a pull request for up to batch messages that are delivered on the Messages() channel of the returned
+// batch. The channel is closed when the batch is complete, the request expired or failed, heartbeats were missed or
+// ctx is cancelled. Control messages are handled internally, the subscription used to receive the messages is
+// always removed once the channel is closed. Up to 1024 messages are buffered, further messages wait to be read
+// until the pull request expires after which the batch fails with ErrFetchNotRead
+func (c *Consumer) Fetch(ctx context.Context, batch int, opts ...PullOpt) (MessageBatch, error) {
+	if !c.IsPullMode() {
+		return nil, fmt.Errorf("consumer %s > %s is not a pull consumer", c.stream, c.name)
+	}
+
+	if batch < 1 {
+		return nil, fmt.Errorf("batch size must be at least 1")
+	}
+
+	req := api.JSApiConsumerGetNextRequest{Batch: batch}
+	for _, opt := range opts {
+		err := opt(&req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// validate against the expiry that will be sent, including the default one
+	c.preparePullRequest(ctx, &req)
+
+	if req.Heartbeat > 0 && req.Expires > 0 && req.Heartbeat > req.Expires/2 {
+		return nil, fmt.Errorf("pull heartbeat must be at most half the pull expiry")
+	}
+
+	buffer := batch
+	if buffer > maxFetchBuffer {
+		buffer = maxFetchBuffer
+	}
+
+	mb := &messageBatch{msgs: make(chan *nats.Msg, buffer)}
+
+	go func() {
+		// delivery to a caller that stopped reading is bounded by the pull request so the goroutine always ends
+		wctx, cancel := context.WithTimeout(ctx, c.pullRequestWait(req))
+		defer cancel()
+
+		var unread bool
+		_, _, err := c.fetchBatch(ctx, req, func(msg *nats.Msg) bool {
+			select {
+			case mb.msgs <- msg:
+				return true
+			case <-wctx.Done():
+				unread = ctx.Err() == nil
+				return false
+			}
+		})
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil && unread {
+			err = ErrFetchNotRead
+		}
+
+		mb.mu.Lock()
+		mb.err = err
+		mb.mu.Unlock()
+
+		close(mb.msgs)
+	}()
+
+	return mb, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestConsumer_Fetch(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	for i := 2; i <= 6; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("FETCH"))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	collect := func(mb jsm.MessageBatch) []string {
+		t.Helper()
+
+		var bodies []string
+		for msg := range mb.Messages() {
+			bodies = append(bodies, string(msg.Data))
+			msg.Ack()
+		}
+
+		return bodies
+	}
+
+	mb, err := c.Fetch(ctx, 4)
+	checkErr(t, err, "fetch failed")
+	bodies := collect(mb)
+	checkErr(t, mb.Error(), "batch failed")
+	if len(bodies) != 4 || bodies[0] != "order 1" || bodies[3] != "order 4" {
+		t.Fatalf("expected 4 messages got %v", bodies)
+	}
+
+	mb, err = c.Fetch(ctx, 10, jsm.PullNoWait())
+	checkErr(t, err, "fetch failed")
+	bodies = collect(mb)
+	checkErr(t, mb.Error(), "batch failed")
+	if len(bodies) != 2 || bodies[1] != "order 6" {
+		t.Fatalf("expected 2 messages got %v", bodies)
+	}
+
+	// large batches do not allocate a buffer for every message up front
+	mb, err = c.Fetch(ctx, 1000000, jsm.PullNoWait())
+	checkErr(t, err, "fetch failed")
+	if cap(mb.Messages()) != 1024 {
+		t.Fatalf("expected a buffer of 1024 messages got %d", cap(mb.Messages()))
+	}
+	bodies = collect(mb)
+	checkErr(t, mb.Error(), "batch failed")
+	if len(bodies) != 0 {
+		t.Fatalf("expected no messages got %v", bodies)
+	}
+
+	mb, err = c.Fetch(ctx, 10, jsm.PullExpires(200*time.Millisecond), jsm.PullHeartbeat(50*time.Millisecond))
+	checkErr(t, err, "fetch failed")
+	bodies = collect(mb)
+	checkErr(t, mb.Error(), "batch failed")
+	if len(bodies) != 0 {
+		t.Fatalf("expected no messages got %v", bodies)
+	}
+
+	_, err = c.Fetch(ctx, 0)
+	if err == nil {
+		t.Fatalf("expected batch size error")
+	}
+
+	// the default expiry is the 1 second manager timeout
+	_, err = c.Fetch(ctx, 1, jsm.PullHeartbeat(time.Second))
+	if err == nil || err.Error() != "pull heartbeat must be at most half the pull expiry" {
+		t.Fatalf("expected heartbeat error got %v", err)
+	}
+
+	_, err = nc.Subscribe("FAKE.CONSUMER.INFO.ORDERS.C1", func(msg *nats.Msg) {
+		msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_info_response","stream_name":"ORDERS","name":"C1","config":{"durable_name":"C1","ack_policy":"explicit","deliver_policy":"all","replay_policy":"instant"}}`))
+	})
+	checkErr(t, err, "subscribe failed")
+	_, err = nc.Subscribe("FAKE.CONSUMER.MSG.NEXT.ORDERS.C1", func(*nats.Msg) {})
+	checkErr(t, err, "subscribe failed")
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+	fc, err := fake.LoadConsumer("ORDERS", "C1")
+	checkErr(t, err, "load failed")
+
+	mb, err = fc.Fetch(ctx, 1, jsm.PullExpires(time.Second), jsm.PullHeartbeat(50*time.Millisecond))
+	checkErr(t, err, "fetch failed")
+	collect(mb)
+	if !errors.Is(mb.Error(), jsm.ErrMissedHeartbeats) {
+		t.Fatalf("expected missed heartbeats got %v", mb.Error())
+	}
+
+	cctx, ccancel := context.WithCancel(ctx)
+	mb, err = fc.Fetch(cctx, 1, jsm.PullExpires(time.Second))
+	checkErr(t, err, "fetch failed")
+	ccancel()
+	collect(mb)
+	if !errors.Is(mb.Error(), context.Canceled) {
+		t.Fatalf("expected cancellation got %v", mb.Error())
+	}
+
+	// delivery is not waiting forever for callers that stop reading
+	for i := 0; i < 1100; i++ {
+		checkErr(t, nc.Publish("ORDERS.unread", []byte("unread")), "publish failed")
+	}
+	_, err = nc.Request("ORDERS.unread", []byte("unread"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	uc, err := mgr.NewConsumer("ORDERS", jsm.DurableName("UNREAD"), jsm.FilterStreamBySubject("ORDERS.unread"), jsm.MaxAckPending(2000))
+	checkErr(t, err, "create failed")
+	mb, err = uc.Fetch(context.Background(), 1100, jsm.PullExpires(100*time.Millisecond))
+	checkErr(t, err, "fetch failed")
+	time.Sleep(1500 * time.Millisecond)
+	bodies = collect(mb)
+	if len(bodies) != 1024 || !errors.Is(mb.Error(), jsm.ErrFetchNotRead) {
+		t.Fatalf("expected 1024 buffered messages and an unread error got %d: %v", len(bodies), mb.Error())
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package jsm
+
+import (
+	"context"
+	"iter"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// iterBatchSize is the number of messages requested by each pull made by Messages()
+const iterBatchSize = 10
+
+// TracedMsg is a message yielded by MessagesContext() along with a context holding its trace headers
+type TracedMsg struct {
+	// Ctx holds the trace headers of Msg, see TraceHeadersFromContext()
+	Ctx context.Context
+	// Msg is the received message, nil when an acknowledgement error is yielded
+	Msg *nats.Msg
+}
+
+// Messages iterates messages from a pull consumer by making repeated pull requests, status messages are handled
+// internally and only data messages or errors are yielded.
+//
+// When autoAck is true each message is acknowledged after the loop body for it completes, including when it breaks, acknowledgement errors are
+// yielded with a nil message. Iteration stops when ctx is cancelled or on errors that can not be recovered from.
+//
+// Use MessagesContext() to receive the trace headers configured using TraceHeaderKeys() with each message
+func (c *Consumer) Messages(ctx context.Context, autoAck bool) iter.Seq2[*nats.Msg, error] {
+	return func(yield func(*nats.Msg, error) bool) {
+		c.iterMessages(ctx, autoAck, yield)
+	}
+}
+
+// MessagesContext behaves like Messages but yields each message with a context holding its trace headers, see
+// TraceHeaderKeys() and TraceHeadersFromContext()
+func (c *Consumer) MessagesContext(ctx context.Context, autoAck bool) iter.Seq2[TracedMsg, error] {
+	return func(yield func(TracedMsg, error) bool) {
+		c.iterMessages(ctx, autoAck, func(msg *nats.Msg, err error) bool {
+			return yield(TracedMsg{Ctx: c.TraceContext(ctx, msg), Msg: msg}, err)
+		})
+	}
+}
+
+func (c *Consumer) iterMessages(ctx context.Context, autoAck bool, yield func(*nats.Msg, error) bool) {
+	ack := autoAck && c.AckPolicy() != api.AckNone
+
+	for ctx.Err() == nil {
+		stop := false
+
+		_, kind, err := c.fetchBatch(ctx, api.JSApiConsumerGetNextRequest{Batch: iterBatchSize}, func(msg *nats.Msg) bool {
+			stop = !yield(msg, nil)
+
+			if ack {
+				err := msg.Ack()
+				if err != nil && !stop {
+					stop = !yield(nil, err)
+				}
+			}
+
+			return !stop
+		})
+		if stop || ctx.Err() != nil {
+			return
+		}
+
+		if err != nil && kind != StatusLeadershipChange {
+			yield(nil, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package jsm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats.go"
+)
+
+func TestConsumer_Messages(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for i := 1; i < 25; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.AckWait(time.Minute))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	seen := 0
+	for msg, err := range c.Messages(ctx, true) {
+		checkErr(t, err, "iteration failed")
+		nfo, err := jsm.ParseJSMsgMetadata(msg)
+		checkErr(t, err, "metadata failed")
+		if nfo.StreamSequence() != uint64(seen+1) {
+			t.Fatalf("expected sequence %d got %d", seen+1, nfo.StreamSequence())
+		}
+
+		seen++
+		if seen == 20 {
+			break
+		}
+	}
+
+	checkErr(t, nc.Flush(), "flush failed")
+	nfo, err := c.State()
+	checkErr(t, err, "state failed")
+	if nfo.AckFloor.Stream != 20 || nfo.NumAckPending != 0 {
+		t.Fatalf("expected 20 acked and none pending got %d and %d", nfo.AckFloor.Stream, nfo.NumAckPending)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	seen = 0
+	for _, err := range c.Messages(ctx, false) {
+		checkErr(t, err, "iteration failed")
+		seen++
+	}
+
+	if seen != 5 {
+		t.Fatalf("expected 5 messages got %d", seen)
+	}
+
+	nfo, err = c.State()
+	checkErr(t, err, "state failed")
+	if nfo.NumAckPending != 5 {
+		t.Fatalf("expected 5 pending acks got %d", nfo.NumAckPending)
+	}
+}
+
+func TestConsumer_MessagesContext(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	msg := nats.NewMsg("ORDERS.new")
+	msg.Data = []byte("order 2")
+	msg.Header.Add("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	msg.Header.Add("other", "ignored")
+	_, err := nc.RequestMsg(msg, time.Second)
+	checkErr(t, err, "publish failed")
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.TraceHeaderKeys("traceparent"))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	traces := map[string]nats.Header{}
+	for tm, err := range c.MessagesContext(ctx, true) {
+		checkErr(t, err, "iteration failed")
+		traces[string(tm.Msg.Data)] = jsm.TraceHeadersFromContext(tm.Ctx)
+	}
+
+	if len(traces) != 2 {
+		t.Fatalf("expected 2 messages got %d", len(traces))
+	}
+	if traces["order 1"] != nil {
+		t.Fatalf("expected no trace headers: %v", traces["order 1"])
+	}
+	if !cmp.Equal(traces["order 2"], nats.Header{"traceparent": []string{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}}) {
+		t.Fatalf("invalid trace headers: %v", traces["order 2"])
+	}
+}
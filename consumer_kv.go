@@ -0,0 +1,56 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var kvBucketNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// NewKVWatchConsumer creates an ephemeral consumer on the stream backing the Key-Value bucket that delivers the
+// latest value of every key matching keyFilter followed by any updates, like a KV watcher. An empty keyFilter
+// watches all keys.
+//
+// The consumer does not acknowledge messages and replays instantly, opts are applied after these defaults and can
+// be used to, for example, set a deliver subject for push delivery
+func (m *Manager) NewKVWatchConsumer(bucket string, keyFilter string, opts ...ConsumerOption) (*Consumer, error) {
+	if !kvBucketNameRe.MatchString(bucket) {
+		return nil, fmt.Errorf("%q is not a valid bucket name", bucket)
+	}
+
+	if keyFilter == "" {
+		keyFilter = ">"
+	}
+
+	stream := "KV_" + bucket
+
+	known, err := m.IsKnownStream(stream)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		return nil, fmt.Errorf("bucket %q does not exist", bucket)
+	}
+
+	defaults := []ConsumerOption{
+		AcknowledgeNone(),
+		ReplayInstantly(),
+		DeliverLastPerSubject(),
+		FilterStreamBySubject(fmt.Sprintf("$KV.%s.%s", bucket, keyFilter)),
+	}
+
+	return m.NewConsumer(stream, append(defaults, opts...)...)
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestManager_NewKVWatchConsumer(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err := mgr.NewKVWatchConsumer("CFG", "")
+	if err == nil || err.Error() != `bucket "CFG" does not exist` {
+		t.Fatalf("expected missing bucket error got %v", err)
+	}
+
+	_, err = mgr.NewStream("KV_CFG", jsm.Subjects("$KV.CFG.>"), jsm.MaxMessagesPerSubject(5), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	for _, key := range []string{"a.1", "a.1", "a.2", "b.1"} {
+		_, err = nc.Request("$KV.CFG."+key, []byte(key), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewKVWatchConsumer("CFG", "a.>")
+	checkErr(t, err, "create failed")
+
+	if !c.IsEphemeral() || c.AckPolicy() != api.AckNone || c.DeliverPolicy() != api.DeliverLastPerSubject || c.FilterSubject() != "$KV.CFG.a.>" {
+		t.Fatalf("invalid configuration: %+v", c.Configuration())
+	}
+
+	pending, err := c.PendingMessages()
+	checkErr(t, err, "state failed")
+	if pending != 2 {
+		t.Fatalf("expected 2 pending messages got %d", pending)
+	}
+
+	_, err = mgr.NewKVWatchConsumer("CFG.X", "")
+	if err == nil {
+		t.Fatalf("expected invalid bucket error")
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+const (
+	// orderedHeartbeat is the idle heartbeat used by ordered consumers unless one is set
+	orderedHeartbeat = 5 * time.Second
+	// orderedInactiveThreshold is how long the server keeps an ordered consumer without interest
+	orderedInactiveThreshold = 5 * time.Minute
+	// orderedMissedHeartbeats is the number of heartbeats that can be missed before an ordered consumer is recreated
+	orderedMissedHeartbeats = 3
+
+	// lastConsumerSeqHeader is set on idle heartbeats to the last consumer sequence delivered
+	lastConsumerSeqHeader = "Nats-Last-Consumer"
+	// consumerStalledHeader is set on idle heartbeats to the subject to respond to when flow control is stalled
+	consumerStalledHeader = "Nats-Consumer-Stalled"
+)
+
+// errOrderedReset indicates an ordered consumer should be created again from the last good sequence
+var errOrderedReset = errors.New("ordered consumer reset required")
+
+// NewOrderedConsumer creates an ephemeral push consumer suitable for reading a stream in order by a single reader
+// using OrderedFetch. The consumer does not acknowledge messages, replays instantly, uses flow control and idle
+// heartbeats, is stored in memory without replicas and is removed by the server after 5 minutes without interest.
+//
+// The heartbeat and inactive threshold can be changed using opts, a deliver subject is generated unless set
+func (m *Manager) NewOrderedConsumer(stream string, opts ...ConsumerOption) (*Consumer, error) {
+	// copied so the ordered consumer option is not written into spare capacity of the caller's slice
+	return m.NewConsumerFromDefault(stream, DefaultConsumer, append(append([]ConsumerOption{}, opts...), orderedConsumer(m.nc))...)
+}
+
+// orderedConsumer adjusts a configuration to be an ordered consumer, it should be the last option applied
+func orderedConsumer(nc *nats.Conn) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if o.Durable != "" {
+			return fmt.Errorf("ordered consumers must be ephemeral")
+		}
+
+		if o.DeliverGroup != "" {
+			return fmt.Errorf("ordered consumers can not have a deliver group")
+		}
+
+		if o.DeliverSubject == "" {
+			o.DeliverSubject = nc.NewInbox()
+		}
+		if o.Heartbeat == 0 {
+			o.Heartbeat = orderedHeartbeat
+		}
+		if o.InactiveThreshold == 0 {
+			o.InactiveThreshold = orderedInactiveThreshold
+		}
+
+		o.AckPolicy = api.AckNone
+		o.ReplayPolicy = api.ReplayInstant
+		o.FlowControl = true
+		o.MaxDeliver = 1
+		o.MemoryStorage = true
+		o.Replicas = 1
+
+		return nil
+	}
+}
+
+// IsOrdered determines if the consumer is configured as an ordered consumer as created by NewOrderedConsumer
+func (c *Consumer) IsOrdered() bool {
+	return c.IsPushMode() && c.IsEphemeral() && c.AckPolicy() == api.AckNone && c.FlowControl() && c.Heartbeat() > 0
+}
+
+// OrderedFetch delivers the messages of an ordered consumer to handler in stream order until ctx is cancelled, when
+// it returns nil, or until handler returns an error.
+//
+// When a gap in the consumer sequence is detected, heartbeats are missed or the consumer is removed, the consumer is
+// deleted and created again starting after the last message passed to handler. The Consumer is updated to refer to
+// the new consumer which will have a different name
+func (c *Consumer) OrderedFetch(ctx context.Context, handler func(msg *nats.Msg) error) error {
+	if !c.IsOrdered() {
+		return fmt.Errorf("consumer %s > %s is not an ordered consumer", c.stream, c.name)
+	}
+
+	if handler == nil {
+		return fmt.Errorf("message handler is required")
+	}
+
+	var streamSeq uint64
+
+	for {
+		err := c.orderedDeliver(ctx, &streamSeq, handler)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case !errors.Is(err, errOrderedReset):
+			return err
+		}
+
+		for {
+			err = c.orderedRecreate(streamSeq)
+			if err == nil {
+				break
+			}
+
+			if !isRetryableError(err) {
+				return err
+			}
+
+			select {
+			case <-time.After(c.Heartbeat()):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// orderedDeliver passes messages in order to handler until a reset is required, streamSeq is updated to the
+// stream sequence of the last message handled
+func (c *Consumer) orderedDeliver(ctx context.Context, streamSeq *uint64, handler func(msg *nats.Msg) error) error {
+	sub, err := c.mgr.nc.SubscribeSync(c.DeliverySubject())
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	var consumerSeq uint64
+	idle := time.Duration(orderedMissedHeartbeats) * c.Heartbeat()
+
+	for {
+		tctx, cancel := context.WithTimeout(ctx, idle)
+		msg, err := sub.NextMsgWithContext(tctx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				return errOrderedReset
+			}
+
+			return err
+		}
+
+		kind, _ := ClassifyStatusMsg(msg)
+		switch kind {
+		case StatusData:
+			meta, err := ParseJSMsgMetadata(msg)
+			if err != nil {
+				return err
+			}
+
+			if meta.ConsumerSequence() != consumerSeq+1 {
+				return errOrderedReset
+			}
+
+			consumerSeq = meta.ConsumerSequence()
+			*streamSeq = meta.StreamSequence()
+
+			err = handler(msg)
+			if err != nil {
+				return err
+			}
+
+		case StatusHeartbeat:
+			if stalled := msg.Header.Get(consumerStalledHeader); stalled != "" {
+				c.mgr.nc.Publish(stalled, nil)
+			}
+
+			last := msg.Header.Get(lastConsumerSeqHeader)
+			if last != "" {
+				seq, err := strconv.ParseUint(last, 10, 64)
+				if err == nil && seq != consumerSeq {
+					return errOrderedReset
+				}
+			}
+
+		case StatusFlowControl:
+			msg.Respond(nil)
+
+		default:
+			return errOrderedReset
+		}
+	}
+}
+
+// orderedRecreate deletes the consumer and creates it again with a new name and deliver subject delivering
+// messages after streamSeq, or according to the original deliver policy when no messages were handled
+func (c *Consumer) orderedRecreate(streamSeq uint64) error {
+	cfg := c.Configuration()
+
+	err := c.Delete()
+	if err != nil && !IsConsumerNotFoundErr(err) && !isRetryableError(err) {
+		return err
+	}
+
+	cfg.Name = ""
+	cfg.DeliverSubject = ""
+	if streamSeq > 0 {
+		resetDeliverPolicy(&cfg)
+		cfg.DeliverPolicy = api.DeliverByStartSequence
+		cfg.OptStartSeq = streamSeq + 1
+	}
+
+	created, err := c.mgr.NewConsumerFromDefault(c.stream, cfg, orderedConsumer(c.mgr.nc))
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.name = created.name
+	c.cfg = created.cfg
+	c.lastInfo = created.lastInfo
+	c.requested = created.requested
+	c.Unlock()
+
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestManager_NewOrderedConsumer(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := mgr.NewOrderedConsumer("ORDERS", jsm.DurableName("X"))
+	if err == nil {
+		t.Fatalf("expected durable ordered consumers to fail")
+	}
+
+	// spare capacity in the caller's options is not written to
+	opts := make([]jsm.ConsumerOption, 1, 2)
+	opts[0] = jsm.AcknowledgeExplicit()
+	c, err := mgr.NewOrderedConsumer("ORDERS", opts...)
+	checkErr(t, err, "create failed")
+	if opts[:2][1] != nil {
+		t.Fatalf("expected the caller's options to be unchanged")
+	}
+
+	if !c.IsOrdered() || c.AckPolicy() != api.AckNone || c.ReplayPolicy() != api.ReplayInstant || c.DeliverySubject() == "" {
+		t.Fatalf("invalid ordered consumer: %+v", c.Configuration())
+	}
+	if c.Heartbeat() != 5*time.Second || !c.MemoryStorage() || c.Replicas() != 1 {
+		t.Fatalf("invalid ordered consumer: %+v", c.Configuration())
+	}
+
+	pull, err := mgr.NewConsumer("ORDERS", jsm.DurableName("PULL"))
+	checkErr(t, err, "create failed")
+	if pull.IsOrdered() {
+		t.Fatalf("expected pull consumers not to be ordered")
+	}
+	err = pull.OrderedFetch(context.Background(), func(*nats.Msg) error { return nil })
+	if err == nil {
+		t.Fatalf("expected fetch from an unordered consumer to fail")
+	}
+}
+
+func TestConsumer_OrderedFetch(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	publish := func(from int, to int) error {
+		for i := from; i <= to; i++ {
+			_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	checkErr(t, publish(2, 2), "publish failed")
+
+	c, err := mgr.NewOrderedConsumer("ORDERS", jsm.IdleHeartbeat(100*time.Millisecond))
+	checkErr(t, err, "create failed")
+	original := c.Name()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var received []string
+	err = c.OrderedFetch(ctx, func(msg *nats.Msg) error {
+		received = append(received, string(msg.Data))
+
+		switch len(received) {
+		case 2:
+			// removing the consumer should cause it to be recreated after missed heartbeats
+			err := c.Delete()
+			if err != nil {
+				return err
+			}
+			return publish(3, 5)
+		case 5:
+			cancel()
+		}
+
+		return nil
+	})
+	checkErr(t, err, "fetch failed")
+
+	if fmt.Sprint(received) != "[order 1 order 2 order 3 order 4 order 5]" {
+		t.Fatalf("unexpected messages: %v", received)
+	}
+
+	if c.Name() == original {
+		t.Fatalf("expected the consumer to be recreated")
+	}
+	if c.StartSequence() != 3 {
+		t.Fatalf("expected the consumer to start at 3 got %d", c.StartSequence())
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// pauseWithContext pauses the consumer until the given time, a zero time resumes the consumer
+func (c *Consumer) pauseWithContext(ctx context.Context, until time.Time) (*api.JSApiConsumerPauseResponse, error) {
+	var req api.JSApiConsumerPauseRequest
+	if !until.IsZero() {
+		req.PauseUntil = &until
+	}
+
+	var resp api.JSApiConsumerPauseResponse
+	err := c.mgr.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiConsumerPauseT, c.stream, c.name), req, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Pause pauses message delivery by the consumer until the given time, a time in the past resumes the consumer. The
+// pause state reported by the server is returned and recorded in the latest state of the consumer
+func (c *Consumer) Pause(until time.Time) (paused bool, remaining time.Duration, err error) {
+	if !until.After(time.Now()) {
+		until = time.Time{}
+	}
+
+	resp, err := c.pauseWithContext(context.Background(), until)
+	if err != nil {
+		return false, 0, err
+	}
+
+	c.recordPause(resp)
+
+	return resp.Paused, resp.PauseRemaining, nil
+}
+
+// Resume resumes message delivery by a paused consumer
+func (c *Consumer) Resume() error {
+	resp, err := c.pauseWithContext(context.Background(), time.Time{})
+	if err != nil {
+		return err
+	}
+
+	c.recordPause(resp)
+
+	if resp.Paused {
+		return fmt.Errorf("consumer %s > %s was not resumed by the server", c.stream, c.name)
+	}
+
+	return nil
+}
+
+// IsPaused loads the consumer state and determines if the consumer is paused
+func (c *Consumer) IsPaused() (bool, error) {
+	nfo, err := c.State()
+	if err != nil {
+		return false, err
+	}
+
+	return nfo.Paused, nil
+}
+
+// recordPause updates the configuration and latest state of the consumer with the pause state in resp
+func (c *Consumer) recordPause(resp *api.JSApiConsumerPauseResponse) {
+	var until *time.Time
+	if resp.Paused {
+		pu := resp.PauseUntil
+		until = &pu
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	cfg := *c.cfg
+	cfg.PauseUntil = until
+	c.cfg = &cfg
+
+	if c.lastInfo != nil {
+		nfo := *c.lastInfo
+		nfo.Paused = resp.Paused
+		nfo.PauseRemaining = resp.PauseRemaining
+		nfo.Config.PauseUntil = until
+		c.lastInfo = &nfo
+	}
+}
+
+// PauseRemaining is how much longer the consumer is paused for, 0 when it is not paused
+func (c *Consumer) PauseRemaining() (time.Duration, error) {
+	nfo, err := c.State()
+	if err != nil {
+		return 0, err
+	}
+
+	if !nfo.Paused {
+		return 0, nil
+	}
+
+	return nfo.PauseRemaining, nil
+}
+
+// ExtendPause extends the pause of a paused consumer by the given duration and returns the time it is now paused until
+func (c *Consumer) ExtendPause(by time.Duration) (time.Time, error) {
+	if by <= 0 {
+		return time.Time{}, fmt.Errorf("pause extension must be positive")
+	}
+
+	nfo, err := c.State()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !nfo.Paused {
+		return time.Time{}, fmt.Errorf("consumer %s > %s is not paused", c.stream, c.name)
+	}
+
+	until := time.Now().Add(nfo.PauseRemaining)
+	if nfo.Config.PauseUntil != nil {
+		until = *nfo.Config.PauseUntil
+	}
+
+	resp, err := c.pauseWithContext(context.Background(), until.Add(by))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !resp.Paused {
+		return time.Time{}, fmt.Errorf("consumer %s > %s was not paused by the server", c.stream, c.name)
+	}
+
+	return resp.PauseUntil, c.Reset()
+}
+
+// MaintenanceWindow is a recurring period during which a consumer should not deliver messages
+type MaintenanceWindow struct {
+	// Start is the time of day the window starts as an offset from midnight
+	Start time.Duration
+	// End is the time of day the window ends as an offset from midnight, windows ending before they start end on the next day
+	End time.Duration
+	// Weekdays are the days the window starts on, every day when empty
+	Weekdays []time.Weekday
+	// Location is the time zone Start and End are in, the zone of the current time when nil
+	Location *time.Location
+}
+
+// Validate checks the window is valid
+func (w MaintenanceWindow) Validate() error {
+	if w.Start < 0 || w.Start >= 24*time.Hour {
+		return fmt.Errorf("maintenance window start must be a time of day")
+	}
+
+	if w.End < 0 || w.End >= 24*time.Hour {
+		return fmt.Errorf("maintenance window end must be a time of day")
+	}
+
+	if w.Start == w.End {
+		return fmt.Errorf("maintenance window start and end can not be the same")
+	}
+
+	return nil
+}
+
+// Next finds the window that is active at now or the next one to start after now
+func (w MaintenanceWindow) Next(now time.Time) (start time.Time, end time.Time, err error) {
+	err = w.Validate()
+	if err != nil {
+		return start, end, err
+	}
+
+	loc := w.Location
+	if loc == nil {
+		loc = now.Location()
+	}
+	now = now.In(loc)
+
+	// starting a day back finds windows that started yesterday and are still active
+	for d := -1; d <= 7; d++ {
+		day := time.Date(now.Year(), now.Month(), now.Day()+d, 0, 0, 0, 0, loc)
+		if !w.startsOn(day.Weekday()) {
+			continue
+		}
+
+		start = day.Add(w.Start)
+		end = day.Add(w.End)
+		if w.End < w.Start {
+			end = end.AddDate(0, 0, 1)
+		}
+
+		if end.After(now) {
+			return start, end, nil
+		}
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("no maintenance window found")
+}
+
+func (w MaintenanceWindow) startsOn(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+
+	for _, wd := range w.Weekdays {
+		if wd == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScheduleMaintenancePause pauses the consumer for the duration of each maintenance window until ctx is cancelled,
+// the server resumes delivery when a window ends. Overlapping windows extend the pause to the latest end.
+//
+// Cancelling ctx stops scheduling future windows but does not resume a consumer paused for an active window
+func (c *Consumer) ScheduleMaintenancePause(ctx context.Context, windows []MaintenanceWindow) error {
+	if len(windows) == 0 {
+		return fmt.Errorf("at least one maintenance window is required")
+	}
+
+	for _, w := range windows {
+		err := w.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		now := time.Now()
+
+		start, end, err := nextMaintenancePeriod(windows, now)
+		if err != nil {
+			return err
+		}
+
+		if start.After(now) {
+			timer := time.NewTimer(time.Until(start))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			}
+		}
+
+		_, err = c.pauseWithContext(ctx, end)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("pausing consumer %s > %s failed: %w", c.stream, c.name, err)
+		}
+
+		timer := time.NewTimer(time.Until(end))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+	}
+}
+
+// nextMaintenancePeriod finds the earliest active or upcoming window and extends it by any windows overlapping it
+func nextMaintenancePeriod(windows []MaintenanceWindow, now time.Time) (start time.Time, end time.Time, err error) {
+	type period struct{ start, end time.Time }
+
+	var periods []period
+	for _, w := range windows {
+		s, e, err := w.Next(now)
+		if err != nil {
+			return start, end, err
+		}
+		periods = append(periods, period{s, e})
+	}
+
+	sort.Slice(periods, func(i, j int) bool { return periods[i].start.Before(periods[j].start) })
+
+	start, end = periods[0].start, periods[0].end
+	for _, p := range periods[1:] {
+		if !p.start.After(end) && p.end.After(end) {
+			end = p.end
+		}
+	}
+
+	return start, end, nil
+}
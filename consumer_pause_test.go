@@ -0,0 +1,257 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestMaintenanceWindow_Next(t *testing.T) {
+	// a wednesday
+	now := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+
+	w := jsm.MaintenanceWindow{Start: 2 * time.Hour, End: 4 * time.Hour}
+	start, end, err := w.Next(now)
+	checkErr(t, err, "next failed")
+	if !start.Equal(time.Date(2024, 1, 4, 2, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 1, 4, 4, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected window %v - %v", start, end)
+	}
+
+	w = jsm.MaintenanceWindow{Start: 11 * time.Hour, End: 13 * time.Hour}
+	start, _, err = w.Next(now)
+	checkErr(t, err, "next failed")
+	if !start.Equal(time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the active window got %v", start)
+	}
+
+	w = jsm.MaintenanceWindow{Start: 22 * time.Hour, End: 13 * time.Hour, Weekdays: []time.Weekday{time.Tuesday}}
+	start, end, err = w.Next(now)
+	checkErr(t, err, "next failed")
+	if !start.Equal(time.Date(2024, 1, 2, 22, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2024, 1, 3, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the window crossing midnight got %v - %v", start, end)
+	}
+
+	w = jsm.MaintenanceWindow{Start: time.Hour, End: 2 * time.Hour, Weekdays: []time.Weekday{time.Monday}}
+	start, _, err = w.Next(now)
+	checkErr(t, err, "next failed")
+	if !start.Equal(time.Date(2024, 1, 8, 1, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected next monday got %v", start)
+	}
+
+	_, _, err = jsm.MaintenanceWindow{Start: time.Hour, End: time.Hour}.Next(now)
+	if err == nil {
+		t.Fatalf("expected an error for an empty window")
+	}
+
+	_, _, err = jsm.MaintenanceWindow{Start: time.Hour, End: 25 * time.Hour}.Next(now)
+	if err == nil {
+		t.Fatalf("expected an error for an invalid end")
+	}
+}
+
+func TestConsumer_ScheduleMaintenancePause(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	err = c.ScheduleMaintenancePause(context.Background(), nil)
+	if err == nil || err.Error() != "at least one maintenance window is required" {
+		t.Fatalf("expected windows error got %v", err)
+	}
+
+	err = c.ScheduleMaintenancePause(context.Background(), []jsm.MaintenanceWindow{{Start: -1, End: time.Hour}})
+	if err == nil || err.Error() != "maintenance window start must be a time of day" {
+		t.Fatalf("expected invalid window error got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := now.Sub(midnight) + time.Hour
+	if start >= 24*time.Hour {
+		start -= 24 * time.Hour
+	}
+
+	err = c.ScheduleMaintenancePause(ctx, []jsm.MaintenanceWindow{{Start: start, End: (start + time.Hour) % (24 * time.Hour)}})
+	checkErr(t, err, "schedule failed")
+}
+
+// fakePausableConsumer responds to consumer info and pause requests for ORDERS > C1 using the FAKE api prefix
+func fakePausableConsumer(t *testing.T, nc *nats.Conn) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var until time.Time
+
+	_, err := nc.Subscribe("FAKE.CONSUMER.INFO.ORDERS.C1", func(msg *nats.Msg) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		nfo := &api.ConsumerInfo{Stream: "ORDERS", Name: "C1", Config: api.ConsumerConfig{Durable: "C1", Name: "C1", AckPolicy: api.AckExplicit}}
+		if time.Now().Before(until) {
+			pu := until
+			nfo.Paused = true
+			nfo.PauseRemaining = time.Until(until)
+			nfo.Config.PauseUntil = &pu
+		}
+
+		resp, _ := json.Marshal(api.JSApiConsumerInfoResponse{JSApiResponse: api.JSApiResponse{Type: "io.nats.jetstream.api.v1.consumer_info_response"}, ConsumerInfo: nfo})
+		msg.Respond(resp)
+	})
+	checkErr(t, err, "subscribe failed")
+
+	_, err = nc.Subscribe("FAKE.CONSUMER.PAUSE.ORDERS.C1", func(msg *nats.Msg) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var req api.JSApiConsumerPauseRequest
+		json.Unmarshal(msg.Data, &req)
+		until = time.Time{}
+		if req.PauseUntil != nil {
+			until = *req.PauseUntil
+		}
+
+		resp, _ := json.Marshal(api.JSApiConsumerPauseResponse{Paused: time.Now().Before(until), PauseUntil: until, PauseRemaining: time.Until(until)})
+		msg.Respond(resp)
+	})
+	checkErr(t, err, "subscribe failed")
+}
+
+func TestConsumer_ExtendPause(t *testing.T) {
+	srv, nc, _ := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	fakePausableConsumer(t, nc)
+
+	mgr, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+
+	c, err := mgr.LoadConsumer("ORDERS", "C1")
+	checkErr(t, err, "load failed")
+
+	remaining, err := c.PauseRemaining()
+	checkErr(t, err, "remaining failed")
+	if remaining != 0 {
+		t.Fatalf("expected no pause got %v", remaining)
+	}
+
+	_, err = c.ExtendPause(time.Minute)
+	if err == nil {
+		t.Fatalf("expected extending an unpaused consumer to fail")
+	}
+
+	nc.Request("FAKE.CONSUMER.PAUSE.ORDERS.C1", []byte(`{"pause_until":"`+time.Now().Add(time.Hour).Format(time.RFC3339Nano)+`"}`), time.Second)
+
+	remaining, err = c.PauseRemaining()
+	checkErr(t, err, "remaining failed")
+	if remaining < 59*time.Minute || remaining > time.Hour {
+		t.Fatalf("expected an hour remaining got %v", remaining)
+	}
+
+	until, err := c.ExtendPause(time.Hour)
+	checkErr(t, err, "extend failed")
+	if d := time.Until(until); d < 119*time.Minute || d > 2*time.Hour {
+		t.Fatalf("expected a pause for 2 hours got %v", d)
+	}
+
+	remaining, err = c.PauseRemaining()
+	checkErr(t, err, "remaining failed")
+	if remaining < 119*time.Minute {
+		t.Fatalf("expected 2 hours remaining got %v", remaining)
+	}
+}
+
+func TestConsumer_PauseResume(t *testing.T) {
+	srv, nc, _ := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	fakePausableConsumer(t, nc)
+
+	mgr, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+
+	c, err := mgr.LoadConsumer("ORDERS", "C1")
+	checkErr(t, err, "load failed")
+
+	paused, err := c.IsPaused()
+	checkErr(t, err, "paused failed")
+	if paused {
+		t.Fatalf("expected consumer to not be paused")
+	}
+
+	paused, remaining, err := c.Pause(time.Now().Add(time.Hour))
+	checkErr(t, err, "pause failed")
+	if !paused || remaining < 59*time.Minute || remaining > time.Hour {
+		t.Fatalf("expected a pause of an hour got %v %v", paused, remaining)
+	}
+
+	nfo, err := c.LatestState()
+	checkErr(t, err, "state failed")
+	if !nfo.Paused || c.Configuration().PauseUntil == nil {
+		t.Fatalf("expected the latest state to be paused: %+v", nfo)
+	}
+
+	paused, err = c.IsPaused()
+	checkErr(t, err, "paused failed")
+	if !paused {
+		t.Fatalf("expected consumer to be paused")
+	}
+
+	paused, _, err = c.Pause(time.Now().Add(-time.Minute))
+	checkErr(t, err, "pause failed")
+	if paused {
+		t.Fatalf("expected a past time to resume the consumer")
+	}
+
+	_, _, err = c.Pause(time.Now().Add(time.Hour))
+	checkErr(t, err, "pause failed")
+
+	bodies := make(chan string, 1)
+	sub, err := nc.Subscribe("FAKE.CONSUMER.PAUSE.ORDERS.C1", func(msg *nats.Msg) { bodies <- string(msg.Data) })
+	checkErr(t, err, "subscribe failed")
+	checkErr(t, c.Resume(), "resume failed")
+	sub.Unsubscribe()
+
+	if body := <-bodies; body != "{}" {
+		t.Fatalf("expected the resume request to not have a pause time got %s", body)
+	}
+
+	nfo, err = c.LatestState()
+	checkErr(t, err, "state failed")
+	if nfo.Paused || c.Configuration().PauseUntil != nil {
+		t.Fatalf("expected the latest state to be resumed: %+v", nfo)
+	}
+
+	paused, err = c.IsPaused()
+	checkErr(t, err, "paused failed")
+	if paused {
+		t.Fatalf("expected consumer to be resumed")
+	}
+}
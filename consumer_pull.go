@@ -0,0 +1,835 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+const (
+	statusHeader      = "Status"
+	descriptionHeader = "Description"
+)
+
+// StatusKind is the kind of message received in response to a pull request or on a push subscription
+type StatusKind int
+
+const (
+	// StatusUnknown is a status message that could not be classified
+	StatusUnknown StatusKind = iota
+	// StatusData is a normal message carrying stream data
+	StatusData
+	// StatusHeartbeat is an idle heartbeat sent while no messages are available
+	StatusHeartbeat
+	// StatusFlowControl is a flow control request for push consumers that should be responded to
+	StatusFlowControl
+	// StatusNoMessages indicates a no_wait pull found no messages
+	StatusNoMessages
+	// StatusTimeout indicates the pull request expired before the batch was filled
+	StatusTimeout
+	// StatusBatchCompleted indicates the requested batch was delivered in full
+	StatusBatchCompleted
+	// StatusMaxBytesExceeded indicates the next message would exceed the max bytes of the pull request
+	StatusMaxBytesExceeded
+	// StatusLimitExceeded indicates the pull request exceeded a limit set on the consumer like MaxWaiting or MaxRequestBatch
+	StatusLimitExceeded
+	// StatusConsumerDeleted indicates the consumer was removed while the pull request was active
+	StatusConsumerDeleted
+	// StatusLeadershipChange indicates the consumer leader changed and outstanding pulls were discarded
+	StatusLeadershipChange
+	// StatusServerShutdown indicates the server is shutting down
+	StatusServerShutdown
+	// StatusBadRequest indicates the pull request was not valid
+	StatusBadRequest
+)
+
+func (k StatusKind) String() string {
+	switch k {
+	case StatusData:
+		return "Data"
+	case StatusHeartbeat:
+		return "Heartbeat"
+	case StatusFlowControl:
+		return "Flow Control"
+	case StatusNoMessages:
+		return "No Messages"
+	case StatusTimeout:
+		return "Timeout"
+	case StatusBatchCompleted:
+		return "Batch Completed"
+	case StatusMaxBytesExceeded:
+		return "Max Bytes Exceeded"
+	case StatusLimitExceeded:
+		return "Limit Exceeded"
+	case StatusConsumerDeleted:
+		return "Consumer Deleted"
+	case StatusLeadershipChange:
+		return "Leadership Change"
+	case StatusServerShutdown:
+		return "Server Shutdown"
+	case StatusBadRequest:
+		return "Bad Request"
+	default:
+		return "Unknown"
+	}
+}
+
+// IsControl indicates the message is a control message that does not terminate a pull request
+func (k StatusKind) IsControl() bool {
+	return k == StatusHeartbeat || k == StatusFlowControl
+}
+
+// IsTerminal indicates no more messages will be delivered for the pull request that produced the message
+func (k StatusKind) IsTerminal() bool {
+	return k != StatusData && !k.IsControl()
+}
+
+// ClassifyStatusMsg decodes the Status and Description headers of a message received from JetStream,
+// reason is the server supplied description, empty for data messages
+func ClassifyStatusMsg(msg *nats.Msg) (kind StatusKind, reason string) {
+	if msg == nil {
+		return StatusUnknown, ""
+	}
+
+	if len(msg.Data) > 0 || msg.Header == nil {
+		return StatusData, ""
+	}
+
+	status := msg.Header.Get(statusHeader)
+	if status == "" {
+		return StatusData, ""
+	}
+
+	reason = msg.Header.Get(descriptionHeader)
+	lreason := strings.ToLower(reason)
+
+	switch status {
+	case "100":
+		if strings.Contains(lreason, "flowcontrol") {
+			return StatusFlowControl, reason
+		}
+		return StatusHeartbeat, reason
+
+	case "404":
+		return StatusNoMessages, reason
+
+	case "408":
+		if strings.Contains(lreason, "bad request") || strings.Contains(lreason, "malformed") || strings.Contains(lreason, "empty request") {
+			return StatusBadRequest, reason
+		}
+		return StatusTimeout, reason
+
+	case "409":
+		switch {
+		case strings.Contains(lreason, "batch completed"):
+			return StatusBatchCompleted, reason
+		case strings.Contains(lreason, "exceeds maxbytes"):
+			return StatusMaxBytesExceeded, reason
+		case strings.Contains(lreason, "exceeded max"):
+			return StatusLimitExceeded, reason
+		case strings.Contains(lreason, "consumer deleted"):
+			return StatusConsumerDeleted, reason
+		case strings.Contains(lreason, "leadership change"):
+			return StatusLeadershipChange, reason
+		case strings.Contains(lreason, "server shutdown"):
+			return StatusServerShutdown, reason
+		}
+
+	case "400":
+		return StatusBadRequest, reason
+	}
+
+	return StatusUnknown, reason
+}
+
+// PendingFromMsg parses the Nats-Pending-Messages and Nats-Pending-Bytes headers found on status messages that
+// terminate a pull request, these indicate how much of the pull request was left unfulfilled. ok is false when
+// the message does not carry pending information
+func PendingFromMsg(msg *nats.Msg) (pendingMsgs uint64, pendingBytes uint64, ok bool) {
+	if msg == nil || msg.Header == nil {
+		return 0, 0, false
+	}
+
+	pm := msg.Header.Get(api.JSPullRequestPendingMsgs)
+	if pm == "" {
+		return 0, 0, false
+	}
+
+	pendingMsgs, err := strconv.ParseUint(pm, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	pb := msg.Header.Get(api.JSPullRequestPendingBytes)
+	if pb != "" {
+		pendingBytes, err = strconv.ParseUint(pb, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+
+	return pendingMsgs, pendingBytes, true
+}
+
+// MsgSizeFromHeader parses the Nats-Msg-Size header set on messages delivered by headers only consumers, this is
+// the size of the original message body that was omitted. ok is false when the header is missing or invalid
+func MsgSizeFromHeader(msg *nats.Msg) (size int64, ok bool) {
+	if msg == nil || msg.Header == nil {
+		return 0, false
+	}
+
+	hdr := msg.Header.Get(api.JSMsgSize)
+	if hdr == "" {
+		return 0, false
+	}
+
+	size, err := strconv.ParseInt(hdr, 10, 64)
+	if err != nil || size < 0 {
+		return 0, false
+	}
+
+	return size, true
+}
+
+// preparePullRequest sets the priority group of req and, unless it is a no wait request, defaults its expiry to the
+// manager timeout limited by the deadline of ctx before clamping it to the consumer maximums
+func (c *Consumer) preparePullRequest(ctx context.Context, req *api.JSApiConsumerGetNextRequest) {
+	c.setPullRequestGroup(req)
+
+	if !req.NoWait && req.Expires == 0 {
+		req.Expires = c.mgr.timeout
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining := time.Until(deadline) - 10*time.Millisecond
+			if remaining < req.Expires {
+				req.Expires = remaining
+			}
+		}
+		if req.Expires < time.Millisecond {
+			req.Expires = time.Millisecond
+		}
+	}
+
+	c.ClampPullRequest(req)
+}
+
+// ClampPullRequest reduces the batch size, max bytes and expiry of req to the maximums configured on the consumer
+// so the server does not reject the request, clamped indicates if any value was changed
+func (c *Consumer) ClampPullRequest(req *api.JSApiConsumerGetNextRequest) (clamped bool) {
+	if req == nil {
+		return false
+	}
+
+	c.Lock()
+	maxBatch := c.cfg.MaxRequestBatch
+	maxBytes := c.cfg.MaxRequestMaxBytes
+	maxExpires := c.cfg.MaxRequestExpires
+	c.Unlock()
+
+	if maxBatch > 0 && req.Batch > maxBatch {
+		req.Batch = maxBatch
+		clamped = true
+	}
+
+	if maxBytes > 0 && req.MaxBytes > maxBytes {
+		req.MaxBytes = maxBytes
+		clamped = true
+	}
+
+	if maxExpires > 0 && req.Expires > maxExpires {
+		req.Expires = maxExpires
+		clamped = true
+	}
+
+	return clamped
+}
+
+// NewConsumerAndVerify creates a pull consumer and verifies it is ready to deliver messages by loading its state from
+// an elected leader, available indicates if messages are pending for the consumer. No messages are consumed while
+// verifying so consumers that do not redeliver, like those using AckNone, can be verified safely.
+//
+// Should the verification fail the consumer is removed and an error is returned
+func (m *Manager) NewConsumerAndVerify(stream string, opts []ConsumerOption, verifyTimeout time.Duration) (consumer *Consumer, available bool, err error) {
+	if verifyTimeout <= 0 {
+		verifyTimeout = m.timeout
+	}
+
+	cfg, err := m.buildConsumerConfiguration(DefaultConsumer, opts...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if cfg.DeliverSubject != "" {
+		return nil, false, fmt.Errorf("only pull consumers can be verified")
+	}
+
+	consumer, err = m.createConsumerFromConfig(context.Background(), stream, cfg)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	nfo, err := consumer.StateFromLeader(ctx)
+	if err != nil {
+		derr := consumer.Delete()
+		if derr != nil {
+			return nil, false, fmt.Errorf("verifying consumer %s failed: %v, removing it also failed: %w", consumer.Name(), err, derr)
+		}
+
+		return nil, false, fmt.Errorf("verifying consumer %s failed, it was removed: %w", consumer.Name(), err)
+	}
+
+	return consumer, nfo.NumPending > 0, nil
+}
+
+// AckBatch acknowledges msgs received from a consumer using policy. With AckAll only the message with the highest
+// stream sequence is acknowledged which acknowledges all earlier messages, with AckExplicit every message is
+// acknowledged in stream sequence order and with AckNone nothing is done.
+//
+// All messages must carry JetStream metadata, when some acknowledgements fail the others are still sent and the
+// returned error lists the stream sequences that failed
+func AckBatch(msgs []*nats.Msg, policy api.AckPolicy) error {
+	if policy == api.AckNone {
+		return nil
+	}
+
+	return ackBatch(msgs, policy, func(msg *nats.Msg) error {
+		return msg.Respond(api.AckAck)
+	})
+}
+
+// AckBatchNext acknowledges msgs like AckBatch but the message with the highest stream sequence is acknowledged
+// using +NXT which also requests the next batch of messages to be delivered to inbox. This saves a round trip per
+// batch in pull loops, the inbox must be subscribed before calling AckBatchNext
+func AckBatchNext(msgs []*nats.Msg, policy api.AckPolicy, inbox string, batch int) error {
+	if policy == api.AckNone {
+		return fmt.Errorf("consumers with acknowledgement policy %s can not request messages using acknowledgements", policy)
+	}
+
+	if inbox == "" {
+		return fmt.Errorf("inbox is required")
+	}
+
+	if batch <= 0 {
+		return fmt.Errorf("batch size must be greater than 0")
+	}
+
+	req, err := json.Marshal(api.JSApiConsumerGetNextRequest{Batch: batch})
+	if err != nil {
+		return err
+	}
+
+	body := append(append(append([]byte{}, api.AckNext...), ' '), req...)
+
+	return ackBatch(msgs, policy, func(msg *nats.Msg) error {
+		return msg.RespondMsg(&nats.Msg{Subject: msg.Reply, Reply: inbox, Data: body})
+	})
+}
+
+// ackBatch sorts msgs by stream sequence and acknowledges them according to policy, the message with the highest
+// stream sequence is acknowledged using last
+func ackBatch(msgs []*nats.Msg, policy api.AckPolicy, last func(*nats.Msg) error) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	type seqMsg struct {
+		seq uint64
+		msg *nats.Msg
+	}
+
+	sorted := make([]seqMsg, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg == nil {
+			return fmt.Errorf("can not acknowledge a nil message")
+		}
+
+		meta, err := ParseJSMsgMetadata(msg)
+		if err != nil {
+			return fmt.Errorf("message does not have JetStream metadata: %w", err)
+		}
+
+		sorted = append(sorted, seqMsg{meta.StreamSequence(), msg})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].seq < sorted[j].seq })
+
+	switch policy {
+	case api.AckAll:
+		final := sorted[len(sorted)-1]
+		err := last(final.msg)
+		if err != nil {
+			return fmt.Errorf("acknowledging stream sequence %d failed: %w", final.seq, err)
+		}
+
+		return nil
+
+	case api.AckExplicit:
+		var failed []string
+		var errs []error
+
+		for i, m := range sorted {
+			var err error
+			if i == len(sorted)-1 {
+				err = last(m.msg)
+			} else {
+				err = m.msg.Respond(api.AckAck)
+			}
+			if err != nil {
+				failed = append(failed, strconv.FormatUint(m.seq, 10))
+				errs = append(errs, err)
+			}
+		}
+
+		if len(errs) > 0 {
+			return fmt.Errorf("acknowledging %d of %d messages failed for stream sequences %s: %w", len(errs), len(sorted), strings.Join(failed, ", "), errors.Join(errs...))
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported acknowledgement policy %s", policy)
+	}
+}
+
+// handleBatchSize is the number of messages requested by each pull made by Handle()
+const handleBatchSize = 10
+
+// Handle fetches messages from a pull consumer and calls fn for each with the delivery attempt of the message until
+// ctx is cancelled. Messages are acknowledged when fn succeeds, when it fails the message is negatively acknowledged
+// for redelivery unless maxAttempts is reached in which case it is terminated. A maxAttempts of 0 never terminates.
+//
+// Handle returns nil once ctx is cancelled or an error when messages could not be fetched or acknowledged
+func (c *Consumer) Handle(ctx context.Context, maxAttempts int, fn func(msg *nats.Msg, attempt int) error) error {
+	if fn == nil {
+		return fmt.Errorf("handler is required")
+	}
+
+	return c.HandleContext(ctx, maxAttempts, func(_ context.Context, msg *nats.Msg, attempt int) error {
+		return fn(msg, attempt)
+	})
+}
+
+// HandleContext behaves like Handle but passes fn a context holding the trace headers of each message, see
+// TraceHeaderKeys() and TraceHeadersFromContext()
+func (c *Consumer) HandleContext(ctx context.Context, maxAttempts int, fn func(ctx context.Context, msg *nats.Msg, attempt int) error) error {
+	if fn == nil {
+		return fmt.Errorf("handler is required")
+	}
+
+	if c.AckPolicy() != api.AckExplicit {
+		return fmt.Errorf("consumer %s > %s must use explicit acknowledgement", c.stream, c.name)
+	}
+
+	for ctx.Err() == nil {
+		var herr error
+
+		_, kind, err := c.fetchBatch(ctx, api.JSApiConsumerGetNextRequest{Batch: handleBatchSize}, func(msg *nats.Msg) bool {
+			meta, err := ParseJSMsgMetadata(msg)
+			if err != nil {
+				herr = err
+				return false
+			}
+
+			attempt := meta.Delivered()
+
+			switch {
+			case fn(c.TraceContext(ctx, msg), msg, attempt) == nil:
+				herr = msg.Respond(api.AckAck)
+			case maxAttempts > 0 && attempt >= maxAttempts:
+				herr = msg.Respond(api.AckTerm)
+			default:
+				herr = msg.Respond(api.AckNak)
+			}
+
+			return herr == nil
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if herr != nil {
+			return herr
+		}
+
+		if err != nil && kind != StatusLeadershipChange {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FetchDownsampled fetches messages from a pull consumer in batches of batch until ctx is cancelled and calls fn for
+// at most one message every stride stream sequences, the first message received is always passed to fn. All
+// messages are acknowledged, including those not passed to fn, so the consumer progresses. When fn fails the
+// message is negatively acknowledged and the error is returned, including the reason the negative acknowledgement
+// failed should it fail.
+//
+// FetchDownsampled returns nil once ctx is cancelled or an error when messages could not be fetched or acknowledged
+func (c *Consumer) FetchDownsampled(ctx context.Context, stride uint64, batch int, fn func(msg *nats.Msg) error) error {
+	if fn == nil {
+		return fmt.Errorf("handler is required")
+	}
+
+	if stride < 1 {
+		return fmt.Errorf("stride must be at least 1")
+	}
+
+	ack := c.AckPolicy() != api.AckNone
+	var last uint64
+
+	for ctx.Err() == nil {
+		var herr error
+
+		_, kind, err := c.fetchBatch(ctx, api.JSApiConsumerGetNextRequest{Batch: batch}, func(msg *nats.Msg) bool {
+			meta, err := ParseJSMsgMetadata(msg)
+			if err != nil {
+				herr = err
+				return false
+			}
+
+			seq := meta.StreamSequence()
+			if last == 0 || seq >= last+stride {
+				last = seq
+
+				herr = fn(msg)
+				if herr != nil {
+					if ack {
+						nerr := msg.Respond(api.AckNak)
+						if nerr != nil {
+							herr = fmt.Errorf("%w, negatively acknowledging the message failed: %v", herr, nerr)
+						}
+					}
+					return false
+				}
+			}
+
+			if ack {
+				herr = msg.Respond(api.AckAck)
+			}
+
+			return herr == nil
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if herr != nil {
+			return herr
+		}
+
+		if err != nil && kind != StatusLeadershipChange {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReplayScaled fetches messages from a pull consumer using instant replay and calls fn for each, pacing the calls so
+// the time between messages is the time between their original stream timestamps divided by factor. A factor of 2
+// replays at twice the original speed and 0.5 at half the speed. Messages are acknowledged when fn succeeds, when it
+// fails the message is negatively acknowledged and the error is returned.
+//
+// Each message is fetched before waiting for its turn so slow replays should use an ack wait longer than the scaled
+// time between messages. ReplayScaled returns nil once ctx is cancelled
+func (c *Consumer) ReplayScaled(ctx context.Context, factor float64, fn func(*nats.Msg) error) error {
+	if fn == nil {
+		return fmt.Errorf("handler is required")
+	}
+
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return fmt.Errorf("replay factor must be greater than 0")
+	}
+
+	if c.ReplayPolicy() != api.ReplayInstant {
+		return fmt.Errorf("consumer %s > %s must use instant replay", c.stream, c.name)
+	}
+
+	ack := c.AckPolicy() != api.AckNone
+
+	var first time.Time
+	var start time.Time
+
+	for ctx.Err() == nil {
+		var msgs []*nats.Msg
+
+		_, kind, err := c.fetchBatch(ctx, api.JSApiConsumerGetNextRequest{Batch: 1}, func(msg *nats.Msg) bool {
+			msgs = append(msgs, msg)
+			return true
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		for _, msg := range msgs {
+			meta, err := ParseJSMsgMetadata(msg)
+			if err != nil {
+				return err
+			}
+
+			if first.IsZero() {
+				first = meta.TimeStamp()
+				start = time.Now()
+			}
+
+			offset := time.Duration(float64(meta.TimeStamp().Sub(first)) / factor)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return nil
+				}
+			}
+
+			err = fn(msg)
+			if err != nil {
+				if ack {
+					msg.Respond(api.AckNak)
+				}
+				return err
+			}
+
+			if ack {
+				err = msg.Respond(api.AckAck)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if err != nil && kind != StatusLeadershipChange {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FetchWithCursor fetches up to batch messages waiting up to expires for the batch to fill, nextStreamSeq is the
+// stream sequence following the last message received and can be stored to resume processing later. When no
+// messages are received nextStreamSeq follows the last message delivered by the consumer
+func (c *Consumer) FetchWithCursor(ctx context.Context, batch int, expires time.Duration) (msgs []*nats.Msg, nextStreamSeq uint64, err error) {
+	_, _, err = c.fetchBatch(ctx, api.JSApiConsumerGetNextRequest{Batch: batch, Expires: expires}, func(msg *nats.Msg) bool {
+		msgs = append(msgs, msg)
+		return true
+	})
+
+	if len(msgs) == 0 {
+		if err != nil {
+			return nil, 0, err
+		}
+
+		nfo, err := c.stateWithContext(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return nil, nfo.Delivered.Stream + 1, nil
+	}
+
+	meta, merr := ParseJSMsgMetadata(msgs[len(msgs)-1])
+	if merr != nil {
+		return msgs, 0, merr
+	}
+
+	return msgs, meta.StreamSequence() + 1, err
+}
+
+// FetchGroupedBySubject fetches up to batch messages waiting up to expires for the batch to fill and groups them by
+// subject, messages keep the order they were received in within their group. When ctx is cancelled during the
+// fetch the messages received so far are returned with the context error
+func (c *Consumer) FetchGroupedBySubject(ctx context.Context, batch int, expires time.Duration) (map[string][]*nats.Msg, error) {
+	groups := make(map[string][]*nats.Msg)
+
+	_, _, err := c.fetchBatch(ctx, api.JSApiConsumerGetNextRequest{Batch: batch, Expires: expires}, func(msg *nats.Msg) bool {
+		groups[msg.Subject] = append(groups[msg.Subject], msg)
+		return true
+	})
+
+	return groups, err
+}
+
+// ScanAll reads every message remaining for a pull consumer that does not acknowledge messages, calling fn for each
+// in order using pull requests of batch messages. It returns nil once no messages remain for the consumer or when
+// ctx is cancelled, and the error returned by fn when processing should stop
+func (c *Consumer) ScanAll(ctx context.Context, batch int, fn func(msg *nats.Msg) error) error {
+	if fn == nil {
+		return fmt.Errorf("handler is required")
+	}
+
+	if c.AckPolicy() != api.AckNone {
+		return fmt.Errorf("consumer %s > %s must not use acknowledgements", c.stream, c.name)
+	}
+
+	for ctx.Err() == nil {
+		var herr error
+		done := false
+
+		received, kind, err := c.fetchBatch(ctx, api.JSApiConsumerGetNextRequest{Batch: batch, NoWait: true}, func(msg *nats.Msg) bool {
+			meta, err := ParseJSMsgMetadata(msg)
+			if err != nil {
+				herr = err
+				return false
+			}
+
+			herr = fn(msg)
+			done = meta.Pending() == 0
+
+			return herr == nil && !done
+		})
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case herr != nil:
+			return herr
+		case err != nil && kind != StatusLeadershipChange:
+			return err
+		case done, received == 0 && kind == StatusNoMessages:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// fetchGrace is how long to wait for a pull request to be terminated by the server after it expires
+const fetchGrace = time.Second
+
+// ErrMissedHeartbeats indicates no idle heartbeats were received for an active pull request that requested them
+var ErrMissedHeartbeats = errors.New("pull request idle heartbeats were missed")
+
+// setPullRequestGroup sets the priority group and thresholds configured on the consumer when req does not set a group
+func (c *Consumer) setPullRequestGroup(req *api.JSApiConsumerGetNextRequest) {
+	c.Lock()
+	groups := c.cfg.PriorityGroups
+	overflow := c.cfg.PriorityPolicy == api.PriorityOverflow
+	minPending, minAckPending, _ := priorityOverflowThresholds(c.cfg)
+	c.Unlock()
+
+	if req.Group == "" && len(groups) > 0 {
+		req.Group = groups[0]
+	}
+	if overflow && req.Group != "" && req.MinPending == 0 && req.MinAckPending == 0 {
+		req.MinPending = minPending
+		req.MinAckPending = minAckPending
+	}
+}
+
+// pullRequestWait is how long to wait for a pull request to complete
+func (c *Consumer) pullRequestWait(req api.JSApiConsumerGetNextRequest) time.Duration {
+	if req.NoWait {
+		return c.mgr.timeout
+	}
+
+	return req.Expires + fetchGrace
+}
+
+// fetchBatch performs a single pull request and passes every data message received to handler until the request
+// completes or handler returns false. Control messages like heartbeats and flow control are handled internally,
+// when req requests heartbeats ErrMissedHeartbeats is returned after 2 heartbeats were missed.
+//
+// The status that terminated the request is returned, err is set for terminal statuses other than the normal
+// completion of a pull request and when ctx is cancelled
+func (c *Consumer) fetchBatch(ctx context.Context, req api.JSApiConsumerGetNextRequest, handler func(msg *nats.Msg) bool) (received int, status StatusKind, err error) {
+	if !c.IsPullMode() {
+		return 0, StatusUnknown, fmt.Errorf("consumer %s > %s is not a pull consumer", c.stream, c.name)
+	}
+
+	if req.Batch < 1 {
+		return 0, StatusUnknown, fmt.Errorf("batch size must be at least 1")
+	}
+
+	c.preparePullRequest(ctx, &req)
+
+	tctx, cancel := context.WithTimeout(ctx, c.pullRequestWait(req))
+	defer cancel()
+
+	inbox := c.mgr.nc.NewInbox()
+	sub, err := c.mgr.nc.SubscribeSync(inbox)
+	if err != nil {
+		return 0, StatusUnknown, err
+	}
+	defer sub.Unsubscribe()
+
+	err = c.NextMsgRequest(inbox, &req)
+	if err != nil {
+		return 0, StatusUnknown, err
+	}
+
+	for {
+		wctx, wcancel := tctx, context.CancelFunc(func() {})
+		if req.Heartbeat > 0 {
+			wctx, wcancel = context.WithTimeout(tctx, 2*req.Heartbeat)
+		}
+
+		msg, err := sub.NextMsgWithContext(wctx)
+		wcancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return received, StatusUnknown, ctx.Err()
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) && tctx.Err() == nil {
+				return received, StatusUnknown, ErrMissedHeartbeats
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				return received, StatusTimeout, nil
+			}
+
+			return received, StatusUnknown, err
+		}
+
+		kind, reason := ClassifyStatusMsg(msg)
+		switch kind {
+		case StatusData:
+			received++
+			if !handler(msg) {
+				return received, StatusData, nil
+			}
+
+			if received >= req.Batch {
+				return received, StatusBatchCompleted, nil
+			}
+
+		case StatusHeartbeat:
+
+		case StatusFlowControl:
+			msg.Respond(nil)
+
+		case StatusNoMessages, StatusTimeout, StatusBatchCompleted, StatusMaxBytesExceeded:
+			return received, kind, nil
+
+		default:
+			return received, kind, fmt.Errorf("pull request failed: %s: %s", kind, reason)
+		}
+	}
+}
@@ -0,0 +1,571 @@
+// Copyright 2023 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func statusMsg(status string, description string) *nats.Msg {
+	msg := nats.NewMsg("inbox")
+	msg.Header.Set("Status", status)
+	if description != "" {
+		msg.Header.Set("Description", description)
+	}
+
+	return msg
+}
+
+func TestClassifyStatusMsg(t *testing.T) {
+	cases := []struct {
+		msg  *nats.Msg
+		kind jsm.StatusKind
+	}{
+		{&nats.Msg{Subject: "x", Data: []byte("hello")}, jsm.StatusData},
+		{nats.NewMsg("x"), jsm.StatusData},
+		{statusMsg("100", "Idle Heartbeat"), jsm.StatusHeartbeat},
+		{statusMsg("100", "FlowControl Request"), jsm.StatusFlowControl},
+		{statusMsg("404", "No Messages"), jsm.StatusNoMessages},
+		{statusMsg("408", "Request Timeout"), jsm.StatusTimeout},
+		{statusMsg("408", "Bad Request"), jsm.StatusBadRequest},
+		{statusMsg("409", "Batch Completed"), jsm.StatusBatchCompleted},
+		{statusMsg("409", "Message Size Exceeds MaxBytes"), jsm.StatusMaxBytesExceeded},
+		{statusMsg("409", "Exceeded MaxWaiting"), jsm.StatusLimitExceeded},
+		{statusMsg("409", "Exceeded MaxRequestBatch of 10"), jsm.StatusLimitExceeded},
+		{statusMsg("409", "Consumer Deleted"), jsm.StatusConsumerDeleted},
+		{statusMsg("409", "Leadership Change"), jsm.StatusLeadershipChange},
+		{statusMsg("409", "Server Shutdown"), jsm.StatusServerShutdown},
+		{statusMsg("409", "Something New"), jsm.StatusUnknown},
+	}
+
+	for _, tc := range cases {
+		kind, reason := jsm.ClassifyStatusMsg(tc.msg)
+		if kind != tc.kind {
+			t.Fatalf("expected %s got %s for %q", tc.kind, kind, reason)
+		}
+
+		if kind == jsm.StatusData && reason != "" {
+			t.Fatalf("expected no reason for data messages got %q", reason)
+		}
+	}
+
+	if !jsm.StatusTimeout.IsTerminal() || jsm.StatusHeartbeat.IsTerminal() || jsm.StatusData.IsTerminal() {
+		t.Fatalf("invalid terminal classification")
+	}
+}
+
+func TestPendingFromMsg(t *testing.T) {
+	_, _, ok := jsm.PendingFromMsg(&nats.Msg{Data: []byte("x")})
+	if ok {
+		t.Fatalf("expected no pending information")
+	}
+
+	msg := statusMsg("408", "Request Timeout")
+	msg.Header.Set("Nats-Pending-Messages", "10")
+	msg.Header.Set("Nats-Pending-Bytes", "1024")
+
+	pm, pb, ok := jsm.PendingFromMsg(msg)
+	if !ok || pm != 10 || pb != 1024 {
+		t.Fatalf("expected 10 and 1024 got %d %d %v", pm, pb, ok)
+	}
+
+	msg.Header.Set("Nats-Pending-Messages", "x")
+	_, _, ok = jsm.PendingFromMsg(msg)
+	if ok {
+		t.Fatalf("expected invalid pending to fail")
+	}
+}
+
+func TestMsgSizeFromHeader(t *testing.T) {
+	_, ok := jsm.MsgSizeFromHeader(&nats.Msg{Data: []byte("x")})
+	if ok {
+		t.Fatalf("expected no size information")
+	}
+
+	msg := nats.NewMsg("x")
+	msg.Header.Set("Nats-Msg-Size", "1024")
+	size, ok := jsm.MsgSizeFromHeader(msg)
+	if !ok || size != 1024 {
+		t.Fatalf("expected 1024 got %d %v", size, ok)
+	}
+
+	msg.Header.Set("Nats-Msg-Size", "-1")
+	_, ok = jsm.MsgSizeFromHeader(msg)
+	if ok {
+		t.Fatalf("expected invalid size to fail")
+	}
+}
+
+func TestConsumer_ClampPullRequest(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.MaxRequestBatch(10), jsm.MaxRequestMaxBytes(1024), jsm.MaxRequestExpires(time.Second))
+	checkErr(t, err, "create failed")
+
+	req := &api.JSApiConsumerGetNextRequest{Batch: 5, MaxBytes: 100, Expires: time.Millisecond}
+	if c.ClampPullRequest(req) {
+		t.Fatalf("expected no clamping")
+	}
+
+	req = &api.JSApiConsumerGetNextRequest{Batch: 100, MaxBytes: 2048, Expires: time.Minute}
+	if !c.ClampPullRequest(req) {
+		t.Fatalf("expected clamping")
+	}
+	if req.Batch != 10 || req.MaxBytes != 1024 || req.Expires != time.Second {
+		t.Fatalf("invalid clamped request: %+v", req)
+	}
+
+	unlimited, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C2"))
+	checkErr(t, err, "create failed")
+	req = &api.JSApiConsumerGetNextRequest{Batch: 100, MaxBytes: 2048, Expires: time.Minute}
+	if unlimited.ClampPullRequest(req) {
+		t.Fatalf("expected no clamping without limits")
+	}
+}
+
+func TestConsumer_FetchWithCursor(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for i := 2; i <= 5; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.AcknowledgeNone())
+	checkErr(t, err, "create failed")
+
+	msgs, next, err := c.FetchWithCursor(context.Background(), 3, time.Second)
+	checkErr(t, err, "fetch failed")
+	if len(msgs) != 3 || next != 4 {
+		t.Fatalf("expected 3 messages and cursor 4 got %d and %d", len(msgs), next)
+	}
+
+	msgs, next, err = c.FetchWithCursor(context.Background(), 3, 100*time.Millisecond)
+	checkErr(t, err, "fetch failed")
+	if len(msgs) != 2 || next != 6 {
+		t.Fatalf("expected 2 messages and cursor 6 got %d and %d", len(msgs), next)
+	}
+
+	msgs, next, err = c.FetchWithCursor(context.Background(), 3, 100*time.Millisecond)
+	checkErr(t, err, "fetch failed")
+	if len(msgs) != 0 || next != 6 {
+		t.Fatalf("expected no messages and cursor 6 got %d and %d", len(msgs), next)
+	}
+}
+
+func TestManager_NewConsumerAndVerify(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, _, err := mgr.NewConsumerAndVerify("ORDERS", []jsm.ConsumerOption{jsm.DeliverySubject("out")}, time.Second)
+	if err == nil {
+		t.Fatalf("expected push consumers to fail")
+	}
+
+	c, available, err := mgr.NewConsumerAndVerify("ORDERS", []jsm.ConsumerOption{jsm.DurableName("FULL")}, time.Second)
+	checkErr(t, err, "create failed")
+	if !available {
+		t.Fatalf("expected a message to be available")
+	}
+
+	// verifying does not consume any messages
+	msg, err := c.NextMsg()
+	checkErr(t, err, "next failed")
+	meta, err := jsm.ParseJSMsgMetadata(msg)
+	checkErr(t, err, "metadata failed")
+	if string(msg.Data) != "order 1" || meta.Delivered() != 1 {
+		t.Fatalf("expected the first delivery of order 1 got %q delivered %d", msg.Data, meta.Delivered())
+	}
+
+	c, available, err = mgr.NewConsumerAndVerify("ORDERS", []jsm.ConsumerOption{jsm.DurableName("NOACK"), jsm.AcknowledgeNone()}, time.Second)
+	checkErr(t, err, "create failed")
+	if !available {
+		t.Fatalf("expected a message to be available")
+	}
+	msg, err = c.NextMsg()
+	checkErr(t, err, "next failed")
+	if string(msg.Data) != "order 1" {
+		t.Fatalf("expected order 1 to be kept for AckNone consumers got %q", msg.Data)
+	}
+
+	_, available, err = mgr.NewConsumerAndVerify("ORDERS", []jsm.ConsumerOption{jsm.DurableName("EMPTY"), jsm.StartWithNextReceived()}, time.Second)
+	checkErr(t, err, "create failed")
+	if available {
+		t.Fatalf("expected no messages to be available")
+	}
+}
+
+func TestConsumer_Handle(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := nc.Request("ORDERS.new", []byte("order 2"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	attempts := map[string][]int{}
+	err = c.Handle(ctx, 3, func(msg *nats.Msg, attempt int) error {
+		attempts[string(msg.Data)] = append(attempts[string(msg.Data)], attempt)
+		if string(msg.Data) == "order 1" {
+			return fmt.Errorf("failed")
+		}
+		return nil
+	})
+	checkErr(t, err, "handle failed")
+
+	if fmt.Sprint(attempts["order 1"]) != "[1 2 3]" || fmt.Sprint(attempts["order 2"]) != "[1]" {
+		t.Fatalf("unexpected attempts: %v", attempts)
+	}
+
+	nfo, err := c.State()
+	checkErr(t, err, "state failed")
+	if nfo.NumAckPending != 0 || nfo.NumPending != 0 {
+		t.Fatalf("expected all messages to be handled: %+v", nfo)
+	}
+}
+
+func TestConsumer_HandleContext(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	msg := nats.NewMsg("ORDERS.new")
+	msg.Data = []byte("order 2")
+	msg.Header.Add("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	msg.Header.Add("other", "ignored")
+	_, err := nc.RequestMsg(msg, time.Second)
+	checkErr(t, err, "publish failed")
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.TraceHeaderKeys("traceparent", "tracestate"))
+	checkErr(t, err, "create failed")
+
+	if !cmp.Equal(c.TraceHeaderKeys(), []string{"traceparent", "tracestate"}) {
+		t.Fatalf("invalid trace header keys: %v", c.TraceHeaderKeys())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	traces := map[string]nats.Header{}
+	err = c.HandleContext(ctx, 0, func(ctx context.Context, msg *nats.Msg, _ int) error {
+		traces[string(msg.Data)] = jsm.TraceHeadersFromContext(ctx)
+		return nil
+	})
+	checkErr(t, err, "handle failed")
+
+	if traces["order 1"] != nil {
+		t.Fatalf("expected no trace headers: %v", traces["order 1"])
+	}
+	if !cmp.Equal(traces["order 2"], nats.Header{"traceparent": []string{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}}) {
+		t.Fatalf("invalid trace headers: %v", traces["order 2"])
+	}
+
+	_, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.TraceHeaderKeys("a,b"))
+	if err == nil {
+		t.Fatalf("expected invalid key error")
+	}
+}
+
+func TestAckBatch(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	for i := 2; i <= 5; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	for _, policy := range []api.AckPolicy{api.AckAll, api.AckExplicit} {
+		t.Run(policy.String(), func(t *testing.T) {
+			c, err := mgr.NewConsumer("ORDERS", jsm.DurableName(policy.String()), jsm.AckWait(time.Minute), func(o *api.ConsumerConfig) error {
+				o.AckPolicy = policy
+				return nil
+			})
+			checkErr(t, err, "create failed")
+
+			var msgs []*nats.Msg
+			for i := 0; i < 5; i++ {
+				msg, err := c.NextMsg()
+				checkErr(t, err, "next failed")
+				msgs = append([]*nats.Msg{msg}, msgs...)
+			}
+
+			checkErr(t, jsm.AckBatch(msgs, policy), "ack failed")
+			checkErr(t, nc.Flush(), "flush failed")
+
+			nfo, err := c.State()
+			checkErr(t, err, "state failed")
+			if nfo.NumAckPending != 0 || nfo.AckFloor.Stream != 5 {
+				t.Fatalf("expected all messages to be acknowledged: %+v", nfo)
+			}
+		})
+	}
+
+	unbound := &nats.Msg{Reply: "$JS.ACK.ORDERS.X.1.7.7.1587466354254920000.0"}
+	err := jsm.AckBatch([]*nats.Msg{unbound}, api.AckExplicit)
+	if err == nil || !strings.Contains(err.Error(), "acknowledging 1 of 1 messages failed for stream sequences 7") {
+		t.Fatalf("expected partial failure got %v", err)
+	}
+
+	err = jsm.AckBatch([]*nats.Msg{nats.NewMsg("x")}, api.AckExplicit)
+	if err == nil {
+		t.Fatalf("expected metadata error")
+	}
+}
+
+func TestAckBatchNext(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	for i := 2; i <= 6; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("NEXT"), jsm.AckWait(time.Minute), jsm.AcknowledgeExplicit())
+	checkErr(t, err, "create failed")
+
+	var msgs []*nats.Msg
+	for i := 0; i < 3; i++ {
+		msg, err := c.NextMsg()
+		checkErr(t, err, "next failed")
+		msgs = append(msgs, msg)
+	}
+
+	inbox := nc.NewRespInbox()
+	sub, err := nc.SubscribeSync(inbox)
+	checkErr(t, err, "subscribe failed")
+
+	err = jsm.AckBatchNext(msgs, api.AckExplicit, "", 2)
+	if err == nil || err.Error() != "inbox is required" {
+		t.Fatalf("expected inbox error got %v", err)
+	}
+
+	err = jsm.AckBatchNext(msgs, api.AckNone, inbox, 2)
+	if err == nil {
+		t.Fatalf("expected ack none error")
+	}
+
+	checkErr(t, jsm.AckBatchNext(msgs, api.AckExplicit, inbox, 2), "ack failed")
+
+	for i := 4; i <= 5; i++ {
+		msg, err := sub.NextMsg(time.Second)
+		checkErr(t, err, "next batch failed")
+		if string(msg.Data) != fmt.Sprintf("order %d", i) {
+			t.Fatalf("expected order %d got %q", i, msg.Data)
+		}
+	}
+
+	nfo, err := c.State()
+	checkErr(t, err, "state failed")
+	if nfo.AckFloor.Stream != 3 || nfo.NumAckPending != 2 {
+		t.Fatalf("expected 3 messages acknowledged and 2 pending: %+v", nfo)
+	}
+}
+
+func TestConsumer_ReplayScaled(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for i := 2; i <= 3; i++ {
+		time.Sleep(400 * time.Millisecond)
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("REPLAY"))
+	checkErr(t, err, "create failed")
+
+	err = c.ReplayScaled(context.Background(), 0, func(msg *nats.Msg) error { return nil })
+	if err == nil {
+		t.Fatalf("expected invalid factor error")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var times []time.Time
+	err = c.ReplayScaled(ctx, 4, func(msg *nats.Msg) error {
+		times = append(times, time.Now())
+		if len(times) == 3 {
+			cancel()
+		}
+		return nil
+	})
+	checkErr(t, err, "replay failed")
+
+	if len(times) != 3 {
+		t.Fatalf("expected 3 messages got %d", len(times))
+	}
+
+	elapsed := times[2].Sub(times[0])
+	if elapsed < 150*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Fatalf("expected replay to take around 200ms got %v", elapsed)
+	}
+}
+
+func TestConsumer_ScanAll(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for i := 2; i <= 25; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	acked, err := mgr.NewConsumer("ORDERS", jsm.DurableName("ACKED"))
+	checkErr(t, err, "create failed")
+	err = acked.ScanAll(context.Background(), 10, func(*nats.Msg) error { return nil })
+	if err == nil {
+		t.Fatalf("expected consumers with acknowledgements to fail")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("SCAN"), jsm.AcknowledgeNone())
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var received []string
+	err = c.ScanAll(ctx, 10, func(msg *nats.Msg) error {
+		received = append(received, string(msg.Data))
+		return nil
+	})
+	checkErr(t, err, "scan failed")
+	if len(received) != 25 || received[0] != "order 1" || received[24] != "order 25" {
+		t.Fatalf("unexpected messages: %v", received)
+	}
+
+	c, err = mgr.NewConsumer("ORDERS", jsm.DurableName("STOP"), jsm.AcknowledgeNone())
+	checkErr(t, err, "create failed")
+	count := 0
+	err = c.ScanAll(ctx, 10, func(msg *nats.Msg) error {
+		count++
+		if count == 5 {
+			return fmt.Errorf("stop")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "stop" || count != 5 {
+		t.Fatalf("expected the handler error after 5 messages got %v after %d", err, count)
+	}
+}
+
+func TestConsumer_FetchGroupedBySubject(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for i, subj := range []string{"ORDERS.old", "ORDERS.new", "ORDERS.old"} {
+		_, err := nc.Request(subj, []byte(fmt.Sprintf("order %d", i+2)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.AcknowledgeNone())
+	checkErr(t, err, "create failed")
+
+	groups, err := c.FetchGroupedBySubject(context.Background(), 10, 100*time.Millisecond)
+	checkErr(t, err, "fetch failed")
+	if len(groups) != 2 || len(groups["ORDERS.new"]) != 2 || len(groups["ORDERS.old"]) != 2 {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+	if string(groups["ORDERS.old"][0].Data) != "order 2" || string(groups["ORDERS.old"][1].Data) != "order 4" {
+		t.Fatalf("expected messages to keep their order")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.FetchGroupedBySubject(ctx, 10, time.Second)
+	if err == nil {
+		t.Fatalf("expected a cancelled context to fail")
+	}
+}
+
+func TestConsumer_FetchDownsampled(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for i := 2; i <= 10; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var sampled []string
+	err = c.FetchDownsampled(ctx, 3, 4, func(msg *nats.Msg) error {
+		sampled = append(sampled, string(msg.Data))
+		return nil
+	})
+	checkErr(t, err, "fetch failed")
+
+	if fmt.Sprint(sampled) != "[order 1 order 4 order 7 order 10]" {
+		t.Fatalf("unexpected samples: %v", sampled)
+	}
+
+	nfo, err := c.State()
+	checkErr(t, err, "state failed")
+	if nfo.NumPending != 0 || nfo.AckFloor.Stream != 10 {
+		t.Fatalf("expected all messages to be acknowledged: %+v", nfo)
+	}
+
+	_, err = nc.Request("ORDERS.new", []byte("order 11"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	// a failed negative acknowledgement is reported with the handler error
+	fnc, err := nats.Connect(srv.ClientURL())
+	checkErr(t, err, "connect failed")
+	fmgr, err := jsm.New(fnc)
+	checkErr(t, err, "manager failed")
+	fc, err := fmgr.LoadConsumer("ORDERS", "C1")
+	checkErr(t, err, "load failed")
+
+	err = fc.FetchDownsampled(context.Background(), 1, 1, func(msg *nats.Msg) error {
+		fnc.Close()
+		return fmt.Errorf("handler failed")
+	})
+	if err == nil || err.Error() != "handler failed, negatively acknowledging the message failed: nats: connection closed" {
+		t.Fatalf("expected nak error got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// TraceHeaderKeysMetadataKey is the metadata key holding the comma separated trace header keys set using TraceHeaderKeys()
+const TraceHeaderKeysMetadataKey = "io.nats.jsm.trace_headers"
+
+// traceHeadersCtxKey is the context key holding trace headers extracted from a message
+type traceHeadersCtxKey struct{}
+
+// TraceHeaderKeys records the message headers that carry trace context, like traceparent and tracestate, in the
+// consumer metadata. Handle() and TraceContext() extract these headers from messages and make them available
+// using TraceHeadersFromContext()
+func TraceHeaderKeys(keys ...string) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if len(keys) == 0 {
+			return fmt.Errorf("at least one trace header key is required")
+		}
+
+		for _, k := range keys {
+			if strings.TrimSpace(k) == "" || strings.Contains(k, ",") {
+				return fmt.Errorf("invalid trace header key %q", k)
+			}
+		}
+
+		meta := make(map[string]string, len(o.Metadata)+1)
+		for k, v := range o.Metadata {
+			meta[k] = v
+		}
+		meta[TraceHeaderKeysMetadataKey] = strings.Join(keys, ",")
+		o.Metadata = meta
+
+		return nil
+	}
+}
+
+// TraceHeaderKeys is the list of trace header keys set using the TraceHeaderKeys() option
+func (c *Consumer) TraceHeaderKeys() []string {
+	return splitSetting(c.Metadata()[TraceHeaderKeysMetadataKey])
+}
+
+// TraceContext creates a context from ctx holding the trace headers of msg as configured using the TraceHeaderKeys()
+// option, use TraceHeadersFromContext() to retrieve them. The context is ctx when no trace headers are configured
+// or found in msg
+func (c *Consumer) TraceContext(ctx context.Context, msg *nats.Msg) context.Context {
+	if msg == nil || len(msg.Header) == 0 {
+		return ctx
+	}
+
+	var hdrs nats.Header
+	for _, k := range c.TraceHeaderKeys() {
+		vals := msg.Header.Values(k)
+		if len(vals) == 0 {
+			continue
+		}
+
+		if hdrs == nil {
+			hdrs = nats.Header{}
+		}
+		for _, v := range vals {
+			hdrs.Add(k, v)
+		}
+	}
+
+	if hdrs == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, traceHeadersCtxKey{}, hdrs)
+}
+
+// TraceHeadersFromContext retrieves the trace headers stored in ctx by TraceContext() or HandleContext(), nil when none were found
+func TraceHeadersFromContext(ctx context.Context) nats.Header {
+	hdrs, _ := ctx.Value(traceHeadersCtxKey{}).(nats.Header)
+	return hdrs
+}
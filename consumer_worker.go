@@ -0,0 +1,219 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+const (
+	// pullWorkerExpires is how long each pull request made by a pull worker stays active
+	pullWorkerExpires = 30 * time.Second
+	// pullWorkerHeartbeat is the idle heartbeat requested by a pull worker, two missed heartbeats restarts pulling
+	pullWorkerHeartbeat = 5 * time.Second
+	// pullWorkerRetry is how long a pull worker waits before pulling again after the consumer rejected a pull
+	pullWorkerRetry = time.Second
+)
+
+// PullWorker is a background worker started using StartPullWorker()
+type PullWorker struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Stop stops the worker, waits for the handler to complete and returns the error that stopped the worker, if any
+func (w *PullWorker) Stop() error {
+	w.cancel()
+	<-w.done
+
+	return w.err
+}
+
+// Done is closed once the worker stopped, either because it was stopped or because of an error, see Err()
+func (w *PullWorker) Done() <-chan struct{} {
+	return w.done
+}
+
+// Err is the error that stopped the worker, nil while running or when stopped using Stop() or its context
+func (w *PullWorker) Err() error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+		return nil
+	}
+}
+
+// StartPullWorker continuously fetches messages from a pull consumer in the background and calls handler for each,
+// in order, until ctx is cancelled or the worker is stopped. Pull requests are made so that up to bufferSize
+// messages are requested ahead of the handler and are replenished as messages arrive, expired pulls, missed
+// heartbeats and transient statuses like leadership changes cause new pulls to be made.
+//
+// The worker stops by itself when the consumer is deleted, pulls are rejected as invalid or the connection is
+// closed, the reason is available from Err() once Done() is closed.
+//
+// The handler is responsible for acknowledging messages and must not call Stop(), which waits for the handler to
+// complete. Pull requests and the subscription use nc, or the connection of the manager when nil
+func (c *Consumer) StartPullWorker(ctx context.Context, nc *nats.Conn, bufferSize int, handler func(*nats.Msg)) (*PullWorker, error) {
+	if !c.IsPullMode() {
+		return nil, fmt.Errorf("consumer %s > %s is not a pull consumer", c.stream, c.name)
+	}
+
+	if bufferSize < 1 {
+		return nil, fmt.Errorf("buffer size must be at least 1")
+	}
+
+	if handler == nil {
+		return nil, fmt.Errorf("handler is required")
+	}
+
+	if nc == nil {
+		nc = c.mgr.nc
+	}
+
+	subject, err := c.mgr.NextSubject(c.stream, c.name)
+	if err != nil {
+		return nil, err
+	}
+
+	inbox := nc.NewInbox()
+	sub, err := nc.SubscribeSync(inbox)
+	if err != nil {
+		return nil, err
+	}
+
+	wctx, cancel := context.WithCancel(ctx)
+	w := &PullWorker{cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(w.done)
+		defer sub.Unsubscribe()
+
+		w.err = c.pullWorker(wctx, nc, subject, sub, bufferSize, handler)
+	}()
+
+	return w, nil
+}
+
+// pullWorker keeps up to bufferSize messages requested using pulls to subject delivered to sub until ctx is cancelled
+// or an error that can not be recovered from is encountered
+func (c *Consumer) pullWorker(ctx context.Context, nc *nats.Conn, subject string, sub *nats.Subscription, bufferSize int, handler func(*nats.Msg)) error {
+	var pending int
+	idle := 2 * pullWorkerHeartbeat
+
+	pull := func() error {
+		req := api.JSApiConsumerGetNextRequest{Batch: bufferSize - pending, Expires: pullWorkerExpires, Heartbeat: pullWorkerHeartbeat}
+		c.setPullRequestGroup(&req)
+		c.ClampPullRequest(&req)
+		if req.Heartbeat > req.Expires/2 {
+			req.Heartbeat = req.Expires / 2
+		}
+		idle = 2 * req.Heartbeat
+
+		jreq, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+
+		err = nc.PublishMsg(&nats.Msg{Subject: subject, Reply: sub.Subject, Data: jreq})
+		if err != nil {
+			return err
+		}
+
+		pending += req.Batch
+
+		return nil
+	}
+
+	retry := func() {
+		pending = 0
+		timer := time.NewTimer(pullWorkerRetry)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+
+	for ctx.Err() == nil {
+		if pending <= bufferSize/2 {
+			err := pull()
+			switch {
+			case errors.Is(err, nats.ErrConnectionClosed):
+				return err
+			case err != nil:
+				retry()
+				continue
+			}
+		}
+
+		tctx, cancel := context.WithTimeout(ctx, idle)
+		msg, err := sub.NextMsgWithContext(tctx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			// pulls against deleted consumers are not answered so heartbeats being missed is the only sign of it
+			_, err = c.mgr.loadConsumerInfoWithContext(ctx, c.stream, c.name)
+			if IsConsumerNotFoundErr(err) {
+				return fmt.Errorf("consumer %s > %s was deleted: %w", c.stream, c.name, err)
+			}
+
+			// heartbeats were missed so the outstanding pulls are assumed to be gone
+			pending = 0
+			continue
+		}
+
+		kind, reason := ClassifyStatusMsg(msg)
+		switch kind {
+		case StatusData:
+			pending--
+			handler(msg)
+
+		case StatusHeartbeat, StatusFlowControl:
+
+		case StatusTimeout, StatusNoMessages, StatusBatchCompleted, StatusMaxBytesExceeded:
+			left, _, ok := PendingFromMsg(msg)
+			if ok {
+				pending -= int(left)
+			} else {
+				pending = 0
+			}
+
+		case StatusLeadershipChange, StatusServerShutdown, StatusLimitExceeded:
+			retry()
+
+		default:
+			return fmt.Errorf("pull from consumer %s > %s failed with status %s: %s", c.stream, c.name, kind, reason)
+		}
+
+		if pending < 0 {
+			pending = 0
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsm_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go"
+)
+
+func TestConsumer_StartPullWorker(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	for i := 2; i <= 20; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("WORKER"), jsm.MaxRequestBatch(3))
+	checkErr(t, err, "create failed")
+
+	_, err = c.StartPullWorker(context.Background(), nil, 0, func(*nats.Msg) {})
+	if err == nil {
+		t.Fatalf("expected buffer size error")
+	}
+
+	received := make(chan string, 100)
+	w, err := c.StartPullWorker(context.Background(), nil, 5, func(msg *nats.Msg) {
+		received <- string(msg.Data)
+		msg.Ack()
+	})
+	checkErr(t, err, "start failed")
+	defer w.Stop()
+
+	expect := func(n int) {
+		t.Helper()
+
+		for i := 1; i <= n; i++ {
+			select {
+			case body := <-received:
+				if body != fmt.Sprintf("order %d", i) {
+					t.Fatalf("expected order %d got %q", i, body)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timeout waiting for order %d", i)
+			}
+		}
+	}
+
+	expect(20)
+
+	_, err = nc.Request("ORDERS.new", []byte("order 21"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	select {
+	case body := <-received:
+		if body != "order 21" {
+			t.Fatalf("expected order 21 got %q", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for order 21")
+	}
+
+	checkErr(t, w.Stop(), "stop failed")
+	checkErr(t, w.Stop(), "stop failed")
+
+	_, err = nc.Request("ORDERS.new", []byte("order 22"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	select {
+	case body := <-received:
+		t.Fatalf("received %q after stop", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestConsumer_StartPullWorkerDeleted(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("WORKER"))
+	checkErr(t, err, "create failed")
+
+	w, err := c.StartPullWorker(context.Background(), nil, 5, func(msg *nats.Msg) { msg.Ack() })
+	checkErr(t, err, "start failed")
+	defer w.Stop()
+
+	if w.Err() != nil {
+		t.Fatalf("expected no error while running got %v", w.Err())
+	}
+
+	// allow the worker to make its first pull
+	time.Sleep(100 * time.Millisecond)
+	checkErr(t, c.Delete(), "delete failed")
+
+	select {
+	case <-w.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("worker did not stop after the consumer was deleted")
+	}
+
+	err = w.Stop()
+	if err == nil || !strings.Contains(err.Error(), "Consumer Deleted") {
+		t.Fatalf("expected consumer deleted error got %v", err)
+	}
+	if w.Err() != err {
+		t.Fatalf("expected Err() to match Stop() got %v", w.Err())
+	}
+}
@@ -17,8 +17,13 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -46,6 +51,17 @@ var SampledDefaultConsumer = api.ConsumerConfig{
 	SampleFrequency: "100%",
 }
 
+var (
+	// ErrNoConsumerName indicates the server did not return the name of a consumer it was asked to create
+	ErrNoConsumerName = errors.New("expected a consumer name but none were generated")
+	// ErrConsumerExistsDifferentConfig indicates a consumer exists and the requested configuration changes settings that can not be updated
+	ErrConsumerExistsDifferentConfig = errors.New("consumer exists with a configuration that can not be updated")
+	// ErrConsumerNameInUse indicates the consumer name is used by another consumer that can not be replaced
+	ErrConsumerNameInUse = errors.New("consumer name already in use")
+	// ErrNoLeader indicates a consumer did not have a leader able to report its state
+	ErrNoLeader = errors.New("consumer has no leader")
+)
+
 // ConsumerOption configures consumers
 type ConsumerOption func(o *api.ConsumerConfig) error
 
@@ -56,17 +72,58 @@ type Consumer struct {
 	cfg      *api.ConsumerConfig
 	mgr      *Manager
 	lastInfo *api.ConsumerInfo
+	// requested is the configuration sent to the server when this handle created or updated the consumer
+	requested *api.ConsumerConfig
 
 	sync.Mutex
 }
 
 // NewConsumerFromDefault creates a new consumer based on a template config that gets modified by opts
 func (m *Manager) NewConsumerFromDefault(stream string, dflt api.ConsumerConfig, opts ...ConsumerOption) (consumer *Consumer, err error) {
+	return m.NewConsumerFromDefaultWithContext(context.Background(), stream, dflt, opts...)
+}
+
+// NewConsumerFromDefaultWithContext creates a new consumer like NewConsumerFromDefault but the requests made to create
+// it are bound by ctx rather than the manager timeout, use this to allow creation more time on busy clusters. When ctx
+// has no deadline the manager timeout applies to each request
+func (m *Manager) NewConsumerFromDefaultWithContext(ctx context.Context, stream string, dflt api.ConsumerConfig, opts ...ConsumerOption) (consumer *Consumer, err error) {
 	if !IsValidName(stream) {
 		return nil, fmt.Errorf("%q is not a valid stream name", stream)
 	}
 
-	cfg, err := NewConsumerConfiguration(dflt, opts...)
+	cfg, err := m.buildConsumerConfiguration(dflt, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.createConsumerFromConfig(ctx, stream, cfg)
+}
+
+// createConsumerFromConfig creates the consumer configured by cfg as produced by buildConsumerConfiguration()
+func (m *Manager) createConsumerFromConfig(ctx context.Context, stream string, cfg *api.ConsumerConfig) (*Consumer, error) {
+	var err error
+
+	if len(m.consumerMetadata) > 0 {
+		meta := make(map[string]string, len(cfg.Metadata)+len(m.consumerMetadata))
+		for k, v := range m.consumerMetadata {
+			meta[k] = v
+		}
+		for k, v := range cfg.Metadata {
+			meta[k] = v
+		}
+		cfg.Metadata = meta
+
+		err = validateMetadataSize(cfg.Metadata, m.consumerMetadataLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if m.minInactiveThreshold > 0 && cfg.Durable == "" && cfg.InactiveThreshold < m.minInactiveThreshold {
+		cfg.InactiveThreshold = m.minInactiveThreshold
+	}
+
+	err = m.resolveMaxAckPendingPerReplica(ctx, stream, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -76,9 +133,39 @@ func (m *Manager) NewConsumerFromDefault(stream string, dflt api.ConsumerConfig,
 		return nil, fmt.Errorf("configuration validation failed: %s", strings.Join(errs, ", "))
 	}
 
+	err = validateHeadersOnly(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateFilterOverlap(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateDeliverLastPerSubject(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := copyConsumerConfig(*cfg)
+
+	err = validatePriorityPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.filterCoverageCheck {
+		err = m.checkFilterCoverage(ctx, stream, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// TODO: Remove this once natscli and the Terraform NATS provider are using update consumer
 	// if we have a single filter subject in the array use the single filter string instead (which will then use the extended create request subject format)
-	if len(cfg.FilterSubjects) == 1 {
+	// unless delivering the last message per subject where the filters are kept as given
+	if len(cfg.FilterSubjects) == 1 && cfg.DeliverPolicy != api.DeliverLastPerSubject {
 		cfg.FilterSubject = cfg.FilterSubjects[0]
 		cfg.FilterSubjects = nil
 	}
@@ -88,22 +175,247 @@ func (m *Manager) NewConsumerFromDefault(stream string, dflt api.ConsumerConfig,
 		Config: *cfg,
 	}
 
-	createdInfo, err := m.createConsumer(req)
+	createdInfo, err := m.createConsumer(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	if createdInfo == nil {
-		return nil, fmt.Errorf("expected a consumer name but none were generated")
-	}
-
 	c := m.consumerFromCfg(stream, createdInfo.Name, &createdInfo.Config)
 	c.lastInfo = createdInfo
+	c.requested = &requested
 
 	return c, nil
 }
 
-func (m *Manager) createConsumer(req api.JSApiConsumerCreateRequest) (info *api.ConsumerInfo, err error) {
+// resolveMaxAckPendingPerReplica sets the max ack pending of cfg using the replica count of stream when it was set
+// using MaxAckPendingPerReplica() and cfg inherits the stream replicas
+func (m *Manager) resolveMaxAckPendingPerReplica(ctx context.Context, stream string, cfg *api.ConsumerConfig) error {
+	perReplica, err := maxAckPendingPerReplica(cfg)
+	if err != nil || perReplica == 0 || cfg.Replicas > 0 {
+		return err
+	}
+
+	nfo, err := m.loadStreamInfoWithContext(ctx, stream, nil)
+	if err != nil {
+		return fmt.Errorf("could not determine replicas for max ack pending per replica: %w", err)
+	}
+
+	replicas := nfo.Config.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+
+	cfg.MaxAckPending = perReplica * replicas
+
+	return nil
+}
+
+// validateHeadersOnly ensures headers only consumers using push delivery settings have a deliver subject
+func validateHeadersOnly(cfg *api.ConsumerConfig) error {
+	if !cfg.HeadersOnly || cfg.DeliverSubject != "" {
+		return nil
+	}
+
+	if cfg.DeliverGroup != "" || cfg.FlowControl || cfg.Heartbeat > 0 {
+		return fmt.Errorf("headers only consumers using push delivery settings require a deliver subject")
+	}
+
+	return nil
+}
+
+// validateFilterOverlap ensures no subject can match more than one of the filter subjects
+func validateFilterOverlap(cfg *api.ConsumerConfig) error {
+	for i, a := range cfg.FilterSubjects {
+		for _, b := range cfg.FilterSubjects[i+1:] {
+			if subjectsOverlap(a, b) {
+				return fmt.Errorf("filter subjects %q and %q overlap", a, b)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateDeliverLastPerSubject ensures consumers delivering the last message per subject have filter subjects
+func validateDeliverLastPerSubject(cfg *api.ConsumerConfig) error {
+	if cfg.DeliverPolicy == api.DeliverLastPerSubject && len(consumerFilterSubjects(cfg)) == 0 {
+		return fmt.Errorf("deliver last per subject requires at least one filter subject")
+	}
+
+	return nil
+}
+
+// validatePriorityOverflow ensures overflow thresholds are valid and only set on consumers with priority groups
+func validatePriorityOverflow(cfg *api.ConsumerConfig) error {
+	minPending, minAckPending, err := priorityOverflowThresholds(cfg)
+	if err != nil {
+		return err
+	}
+
+	if minPending == 0 && minAckPending == 0 {
+		return nil
+	}
+
+	if len(cfg.PriorityGroups) == 0 {
+		return fmt.Errorf("priority overflow thresholds require a priority group")
+	}
+
+	return nil
+}
+
+// priorityGroupRe matches the priority group names accepted by the server
+var priorityGroupRe = regexp.MustCompile(`^[a-zA-Z0-9/_=-]{1,16}$`)
+
+// validatePriorityPolicy ensures consumers using a priority policy have priority groups
+func validatePriorityPolicy(cfg *api.ConsumerConfig) error {
+	if cfg.PriorityPolicy != api.PriorityNone && len(cfg.PriorityGroups) == 0 {
+		return fmt.Errorf("priority policy %s requires at least one priority group", cfg.PriorityPolicy)
+	}
+
+	return nil
+}
+
+// checkFilterCoverage ensures at least one filter subject overlaps a subject of the stream, streams without
+// subjects like mirrors are not checked as their messages carry the subjects of their origin
+func (m *Manager) checkFilterCoverage(ctx context.Context, stream string, cfg *api.ConsumerConfig) error {
+	filters := consumerFilterSubjects(cfg)
+	if len(filters) == 0 {
+		return nil
+	}
+
+	nfo, err := m.loadStreamInfoWithContext(ctx, stream, nil)
+	if err != nil {
+		return err
+	}
+
+	return validateFilterCoverage(nfo, cfg)
+}
+
+// validateFilterCoverage ensures at least one filter subject overlaps a subject of the stream described by nfo
+func validateFilterCoverage(nfo *api.StreamInfo, cfg *api.ConsumerConfig) error {
+	filters := consumerFilterSubjects(cfg)
+	if len(filters) == 0 || len(nfo.Config.Subjects) == 0 {
+		return nil
+	}
+
+	for _, filter := range filters {
+		for _, subject := range nfo.Config.Subjects {
+			if subjectsOverlap(filter, subject) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("consumer filter subjects %s do not match any subject in stream %s", strings.Join(filters, ", "), nfo.Config.Name)
+}
+
+// consumerFilterSubjects combines the single and multiple filter subject settings
+func consumerFilterSubjects(cfg *api.ConsumerConfig) []string {
+	var filters []string
+	if cfg.FilterSubject != "" {
+		filters = append(filters, cfg.FilterSubject)
+	}
+
+	return append(filters, cfg.FilterSubjects...)
+}
+
+// DryRunConsumer validates cfg as a consumer on stream without creating it and returns a list of problems that
+// would prevent it from being created, an empty list means the consumer is expected to be created successfully.
+//
+// The server does not support validating a consumer without creating it so this is done client side, the
+// configuration is validated using the cross field checks performed by NewConsumerFromDefault and using JSON
+// Schema validation when the Manager has a validator set using WithAPIValidation(). The stream subjects, the
+// stream and account consumer limits and, when a consumer with the same name exists, the fields that cannot be
+// updated are checked against the server. An error is only returned when the server could not be queried
+func (m *Manager) DryRunConsumer(stream string, cfg api.ConsumerConfig) ([]string, error) {
+	if !IsValidName(stream) {
+		return nil, fmt.Errorf("%q is not a valid stream name", stream)
+	}
+
+	var issues []string
+
+	name := cfg.Name
+	if cfg.Durable != "" {
+		if name != "" && name != cfg.Durable {
+			issues = append(issues, fmt.Sprintf("durable name %q does not match name %q", cfg.Durable, name))
+		}
+		name = cfg.Durable
+	}
+	if name != "" && !IsValidName(name) {
+		issues = append(issues, fmt.Sprintf("%q is not a valid consumer name", name))
+	}
+
+	_, errs := cfg.Validate(m.validator)
+	issues = append(issues, errs...)
+
+	metadataSize := func(cfg *api.ConsumerConfig) error { return validateMetadataSize(cfg.Metadata, 0) }
+	for _, check := range []func(*api.ConsumerConfig) error{validateHeadersOnly, validateFilterOverlap, validateDeliverLastPerSubject, validatePriorityPolicy, metadataSize} {
+		err := check(&cfg)
+		if err != nil {
+			issues = append(issues, err.Error())
+		}
+	}
+
+	nfo, err := m.loadStreamInfo(stream, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateFilterCoverage(nfo, &cfg)
+	if err != nil {
+		issues = append(issues, err.Error())
+	}
+
+	exists := false
+	if name != "" {
+		existing, err := m.loadConsumerInfo(stream, name)
+		switch {
+		case err == nil:
+			exists = true
+			mutable := map[string]bool{}
+			for _, f := range ConsumerMutableFields() {
+				mutable[f] = true
+			}
+			for _, d := range consumerConfigFieldDifferences(existing.Config, cfg, true) {
+				if !mutable[d.field] {
+					issues = append(issues, fmt.Sprintf("consumer %s exists and %s cannot be changed from %s to %s", name, d.field, d.live, d.desired))
+				}
+			}
+		case !IsConsumerNotFoundErr(err):
+			return nil, err
+		}
+	}
+
+	if exists {
+		return issues, nil
+	}
+
+	if nfo.Config.MaxConsumers > 0 && nfo.State.Consumers >= nfo.Config.MaxConsumers {
+		issues = append(issues, fmt.Sprintf("stream %s has reached its limit of %d consumers", stream, nfo.Config.MaxConsumers))
+	}
+
+	acct, err := m.JetStreamAccountInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	if acct.Limits.MaxConsumers > 0 && acct.Consumers >= acct.Limits.MaxConsumers {
+		issues = append(issues, fmt.Sprintf("account has reached its limit of %d consumers", acct.Limits.MaxConsumers))
+	}
+
+	replicas := cfg.Replicas
+	if replicas == 0 {
+		replicas = nfo.Config.Replicas
+	}
+	tier, ok := acct.Tiers[fmt.Sprintf("R%d", replicas)]
+	if ok && tier.Limits.MaxConsumers > 0 && tier.Consumers >= tier.Limits.MaxConsumers {
+		issues = append(issues, fmt.Sprintf("account has reached its limit of %d R%d consumers", tier.Limits.MaxConsumers, replicas))
+	}
+
+	return issues, nil
+}
+
+func (m *Manager) createConsumer(ctx context.Context, req api.JSApiConsumerCreateRequest) (info *api.ConsumerInfo, err error) {
 	var resp api.JSApiConsumerCreateResponse
 
 	if req.Config.Name == "" {
@@ -117,14 +429,44 @@ func (m *Manager) createConsumer(req api.JSApiConsumerCreateRequest) (info *api.
 		subj = fmt.Sprintf(api.JSApiConsumerCreateExT, req.Stream, req.Config.Name, req.Config.FilterSubject)
 	}
 
-	err = m.jsonRequest(subj, req, &resp)
+	err = m.jsonRequestWithContext(ctx, subj, req, &resp)
 	if err != nil {
-		return nil, err
+		return nil, consumerCreateError(err)
+	}
+
+	// errors reported by the server are returned by jsonRequest, this handles successful responses without a consumer
+	if resp.ConsumerInfo == nil || resp.ConsumerInfo.Name == "" {
+		return nil, fmt.Errorf("%w: stream %s name %q durable %q response type %q", ErrNoConsumerName, req.Stream, req.Config.Name, req.Config.Durable, resp.Type)
 	}
 
 	return resp.ConsumerInfo, nil
 }
 
+// consumerCreateError wraps errors the server reports when a consumer already exists in ErrConsumerExistsDifferentConfig
+// or ErrConsumerNameInUse, the original error is also wrapped so IsNatsError() still matches the server error code
+func consumerCreateError(err error) error {
+	var apiErr api.ApiError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	switch apiErr.ErrCode {
+	case 10148: // consumer already exists, sent for creates that do not allow updates
+		return fmt.Errorf("%w: %w", ErrConsumerExistsDifferentConfig, err)
+
+	case 10012: // general create failure, used for settings that can not be changed on existing consumers
+		desc := strings.ToLower(apiErr.Description)
+		if strings.Contains(desc, "can not be updated") || strings.Contains(desc, "cannot be updated") || strings.Contains(desc, "can not update") {
+			return fmt.Errorf("%w: %w", ErrConsumerExistsDifferentConfig, err)
+		}
+
+	case 10013, 10105: // consumer name already in use, consumer already exists and is still active
+		return fmt.Errorf("%w: %w", ErrConsumerNameInUse, err)
+	}
+
+	return err
+}
+
 // NewConsumer creates a consumer based on DefaultConsumer modified by opts
 func (m *Manager) NewConsumer(stream string, opts ...ConsumerOption) (consumer *Consumer, err error) {
 	if !IsValidName(stream) {
@@ -150,105 +492,421 @@ func (m *Manager) LoadOrNewConsumerFromDefault(stream string, name string, templ
 	}
 
 	c, err := m.LoadConsumer(stream, name)
-	if IsNatsError(err, 10014) {
+	if IsConsumerNotFoundErr(err) {
 		return m.NewConsumerFromDefault(stream, template, opts...)
 	}
 
 	return c, err
 }
 
-// LoadConsumer loads a consumer by name
-func (m *Manager) LoadConsumer(stream string, name string) (consumer *Consumer, err error) {
-	if !IsValidName(stream) {
-		return nil, fmt.Errorf("%q is not a valid stream name", stream)
+// SwapConsumers exchanges the names of two existing consumers by recreating each one under the name of the other.
+//
+// JetStream does not support renaming consumers so both consumers are deleted and recreated, the recreated
+// consumers resume delivery after the acknowledgement floor of the consumer they replace but all other ack
+// state like pending acknowledgements and redelivery counts are reset. Should any step fail an attempt is made
+// to restore the original consumer and the error describes which consumers were already replaced.
+func (m *Manager) SwapConsumers(stream string, a string, b string) error {
+	if a == b {
+		return fmt.Errorf("can not swap consumer %q with itself", a)
 	}
 
-	if !IsValidName(name) {
-		return nil, fmt.Errorf("%q is not a valid consumer name", name)
+	ca, err := m.LoadConsumer(stream, a)
+	if err != nil {
+		return err
 	}
 
-	consumer = m.consumerFromCfg(stream, name, &api.ConsumerConfig{})
-
-	err = m.loadConfigForConsumer(consumer)
+	cb, err := m.LoadConsumer(stream, b)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return consumer, nil
-}
+	origA, err := swapConsumerConfig(ca, a)
+	if err != nil {
+		return err
+	}
+	origB, err := swapConsumerConfig(cb, b)
+	if err != nil {
+		return err
+	}
+	newA, err := swapConsumerConfig(cb, a)
+	if err != nil {
+		return err
+	}
+	newB, err := swapConsumerConfig(ca, b)
+	if err != nil {
+		return err
+	}
 
-func (m *Manager) consumerFromCfg(stream string, name string, cfg *api.ConsumerConfig) *Consumer {
-	if name == "" && cfg.Name != "" {
-		name = cfg.Name
+	err = m.replaceConsumer(stream, ca, newA, origA)
+	if err != nil {
+		return fmt.Errorf("swapping consumers %s and %s failed, no consumers were swapped: %w", a, b, err)
 	}
 
-	return &Consumer{
-		name:   name,
-		stream: stream,
-		cfg:    cfg,
-		mgr:    m,
+	err = m.replaceConsumer(stream, cb, newB, origB)
+	if err != nil {
+		return fmt.Errorf("swapping consumers %s and %s failed, %s was replaced but %s was not: %w", a, b, a, b, err)
 	}
-}
 
-// NewConsumerConfiguration generates a new configuration based on template modified by opts
-func NewConsumerConfiguration(dflt api.ConsumerConfig, opts ...ConsumerOption) (*api.ConsumerConfig, error) {
-	cfg := dflt
+	return nil
+}
 
-	for _, o := range opts {
-		err := o(&cfg)
-		if err != nil {
-			return nil, err
-		}
+// replaceConsumer deletes c and creates cfg in its place, restoring orig when the create fails
+func (m *Manager) replaceConsumer(stream string, c *Consumer, cfg api.ConsumerConfig, orig api.ConsumerConfig) error {
+	err := c.Delete()
+	if err != nil {
+		return fmt.Errorf("deleting %s failed: %w", c.Name(), err)
 	}
 
-	if cfg.Durable != "" {
-		cfg.Name = cfg.Durable
+	_, err = m.NewConsumerFromDefault(stream, cfg)
+	if err == nil {
+		return nil
 	}
 
-	if cfg.Name == "" {
-		cfg.Name = generateConsName()
+	_, rerr := m.NewConsumerFromDefault(stream, orig)
+	if rerr != nil {
+		return fmt.Errorf("recreating %s failed: %v, restoring the original configuration also failed: %w", c.Name(), err, rerr)
 	}
 
-	return &cfg, nil
+	return fmt.Errorf("recreating %s failed, the original configuration was restored: %w", c.Name(), err)
 }
 
-const rdigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-const base = 62
-
-func generateConsName() string {
-	name := nuid.Next()
-	sha := sha256.New()
-	sha.Write([]byte(name))
-	b := sha.Sum(nil)
-	for i := 0; i < 8; i++ {
-		b[i] = rdigits[int(b[i]%base)]
+// swapConsumerConfig is the configuration of c using name, starting after the ack floor of c
+func swapConsumerConfig(c *Consumer, name string) (api.ConsumerConfig, error) {
+	nfo, err := c.LatestState()
+	if err != nil {
+		return api.ConsumerConfig{}, err
 	}
-	return string(b[:8])
-}
 
-func (m *Manager) loadConfigForConsumer(consumer *Consumer) (err error) {
-	info, err := m.loadConsumerInfo(consumer.stream, consumer.name)
-	if err != nil {
-		return err
+	cfg := c.Configuration()
+	cfg.Name = name
+	if cfg.Durable != "" {
+		cfg.Durable = name
 	}
 
-	consumer.Lock()
-	consumer.cfg = &info.Config
-	consumer.lastInfo = &info
-	consumer.Unlock()
+	if nfo.AckFloor.Stream > 0 {
+		resetDeliverPolicy(&cfg)
+		cfg.DeliverPolicy = api.DeliverByStartSequence
+		cfg.OptStartSeq = nfo.AckFloor.Stream + 1
+	}
 
-	return nil
+	return cfg, nil
 }
 
-func (m *Manager) loadConsumerInfo(s string, c string) (info api.ConsumerInfo, err error) {
-	var resp api.JSApiConsumerInfoResponse
-	err = m.jsonRequest(fmt.Sprintf(api.JSApiConsumerInfoT, s, c), nil, &resp)
+// NewConsumerOnTag creates a consumer that runs on servers with tag. JetStream does not support placing consumers
+// independently of their stream so this requires the stream placement to include tag.
+//
+// Once created the tags of the server leading the consumer are requested and verified reports if it has tag, this
+// requires access to the system account so when the tags can not be requested the consumer is returned unverified
+func (m *Manager) NewConsumerOnTag(stream string, tag string, opts ...ConsumerOption) (consumer *Consumer, verified bool, err error) {
+	if tag == "" {
+		return nil, false, fmt.Errorf("a placement tag is required")
+	}
+
+	str, err := m.LoadStream(stream)
 	if err != nil {
-		return info, err
+		return nil, false, err
 	}
 
-	return *resp.ConsumerInfo, nil
-}
+	placement := str.Configuration().Placement
+	tagged := false
+	if placement != nil {
+		for _, t := range placement.Tags {
+			if t == tag {
+				tagged = true
+				break
+			}
+		}
+	}
+	if !tagged {
+		return nil, false, fmt.Errorf("consumer placement is not supported, consumers are placed with their stream and stream %s is not placed using tag %q", stream, tag)
+	}
+
+	consumer, err = m.NewConsumer(stream, opts...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	nfo, err := consumer.LatestState()
+	if err != nil || nfo.Cluster == nil || nfo.Cluster.Leader == "" {
+		return consumer, false, nil
+	}
+
+	tags, err := m.serverTags(nfo.Cluster.Leader)
+	if err != nil {
+		return consumer, false, nil
+	}
+
+	for _, t := range tags {
+		if t == tag {
+			return consumer, true, nil
+		}
+	}
+
+	return consumer, false, nil
+}
+
+// serverTags requests the tags of the server called name using the system account
+func (m *Manager) serverTags(name string) ([]string, error) {
+	req, err := json.Marshal(map[string]string{"server_name": name})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := m.request("$SYS.REQ.SERVER.PING.VARZ", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Server struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		} `json:"server"`
+	}
+
+	err = json.Unmarshal(res.Data, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Server.Name != name {
+		return nil, fmt.Errorf("received information for server %q while requesting %q", resp.Server.Name, name)
+	}
+
+	return resp.Server.Tags, nil
+}
+
+// SyncConsumerReplicasToStream updates consumers created using FollowStreamReplicas() that override their replica
+// count to match the current replica count of stream, consumers that inherit the stream replica count are managed
+// by the server. The names of updated consumers are returned with any errors keyed by consumer name
+func (m *Manager) SyncConsumerReplicasToStream(stream string) (updated []string, errs map[string]error) {
+	errs = make(map[string]error)
+
+	str, err := m.LoadStream(stream)
+	if err != nil {
+		errs[stream] = err
+		return nil, errs
+	}
+
+	consumers, _, err := m.Consumers(stream)
+	if err != nil {
+		errs[stream] = err
+		return nil, errs
+	}
+
+	replicas := str.Replicas()
+
+	for _, c := range consumers {
+		if !c.FollowsStreamReplicas() || c.Replicas() == 0 || c.Replicas() == replicas {
+			continue
+		}
+
+		// streams can have an even replica count which ConsumerOverrideReplicas rejects
+		err = c.UpdateConfiguration(func(o *api.ConsumerConfig) error {
+			o.Replicas = replicas
+			return nil
+		})
+		if err != nil {
+			errs[c.Name()] = err
+			continue
+		}
+
+		updated = append(updated, c.Name())
+	}
+
+	return updated, errs
+}
+
+// UpdateConsumersWhere applies opts to every consumer on stream that pred matches, all consumers match when pred is
+// nil. Only durable consumers are updated, matched ephemeral consumers are reported in errs along with any other
+// failures keyed by consumer name, or by stream name when the consumers could not be listed
+func (m *Manager) UpdateConsumersWhere(stream string, pred func(api.ConsumerInfo) bool, opts ...ConsumerOption) (updated []string, errs map[string]error) {
+	errs = make(map[string]error)
+
+	consumers, _, err := m.Consumers(stream)
+	if err != nil {
+		errs[stream] = err
+		return nil, errs
+	}
+
+	for _, c := range consumers {
+		nfo, err := c.LatestState()
+		if err != nil {
+			errs[c.Name()] = err
+			continue
+		}
+
+		if pred != nil && !pred(nfo) {
+			continue
+		}
+
+		if c.IsEphemeral() {
+			errs[c.Name()] = fmt.Errorf("consumer %s is not durable and can not be updated", c.Name())
+			continue
+		}
+
+		err = c.UpdateConfiguration(opts...)
+		if err != nil {
+			errs[c.Name()] = err
+			continue
+		}
+
+		updated = append(updated, c.Name())
+	}
+
+	return updated, errs
+}
+
+// LoadConsumer loads a consumer by name
+func (m *Manager) LoadConsumer(stream string, name string) (consumer *Consumer, err error) {
+	if !IsValidName(stream) {
+		return nil, fmt.Errorf("%q is not a valid stream name", stream)
+	}
+
+	if !IsValidName(name) {
+		return nil, fmt.Errorf("%q is not a valid consumer name", name)
+	}
+
+	consumer = m.consumerFromCfg(stream, name, &api.ConsumerConfig{})
+
+	err = m.loadConfigForConsumer(consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	return consumer, nil
+}
+
+func (m *Manager) consumerFromCfg(stream string, name string, cfg *api.ConsumerConfig) *Consumer {
+	if name == "" && cfg.Name != "" {
+		name = cfg.Name
+	}
+
+	return &Consumer{
+		name:   name,
+		stream: stream,
+		cfg:    cfg,
+		mgr:    m,
+	}
+}
+
+// NewConsumerConfiguration generates a new configuration based on template modified by opts.
+//
+// Settings that depend on the final configuration, like InactiveThresholdMultiple(), are resolved once all options
+// are applied. MaxAckPendingPerReplica() requires a replica count as the stream replicas are only known when
+// creating a consumer, use the Manager to create consumers inheriting the stream replicas
+func NewConsumerConfiguration(dflt api.ConsumerConfig, opts ...ConsumerOption) (*api.ConsumerConfig, error) {
+	var m *Manager
+
+	cfg, err := m.buildConsumerConfiguration(dflt, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	perReplica, err := maxAckPendingPerReplica(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if perReplica > 0 && cfg.Replicas == 0 {
+		return nil, fmt.Errorf("max ack pending per replica requires a replica count when not creating a consumer")
+	}
+
+	return cfg, nil
+}
+
+// buildConsumerConfiguration applies opts to dflt and resolves the settings recorded in the metadata by options that
+// depend on the final configuration. The metadata limits of m apply, m may be nil to use the server limits
+func (m *Manager) buildConsumerConfiguration(dflt api.ConsumerConfig, opts ...ConsumerOption) (*api.ConsumerConfig, error) {
+	cfg := &api.ConsumerConfig{}
+	*cfg = dflt
+
+	for _, o := range opts {
+		err := o(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := resolveConsumerMetadataSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// consumers are named in one of three ways: durable consumers always use their durable name as name, named
+	// ephemeral consumers keep the name set using NamedEphemeral() or ConsumerName() and are marked as such in the
+	// metadata while anonymous ephemeral consumers get a generated name
+	switch {
+	case cfg.Durable != "":
+		cfg.Name = cfg.Durable
+		deleteConsumerMetadata(cfg, NamedEphemeralMetadataKey)
+	case cfg.Name == "":
+		cfg.Name = generateConsName()
+		deleteConsumerMetadata(cfg, NamedEphemeralMetadataKey)
+	default:
+		setConsumerMetadata(cfg, NamedEphemeralMetadataKey, "true")
+	}
+
+	var limit int
+	var allowReserved bool
+	if m != nil {
+		limit = m.consumerMetadataLimit
+		allowReserved = m.reservedConsumerMetadata
+	}
+
+	err = validateReservedMetadata(dflt, cfg, allowReserved)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateMetadataSize(cfg.Metadata, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+const rdigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+const base = 62
+
+func generateConsName() string {
+	name := nuid.Next()
+	sha := sha256.New()
+	sha.Write([]byte(name))
+	b := sha.Sum(nil)
+	for i := 0; i < 8; i++ {
+		b[i] = rdigits[int(b[i]%base)]
+	}
+	return string(b[:8])
+}
+
+func (m *Manager) loadConfigForConsumer(consumer *Consumer) (err error) {
+	info, err := m.loadConsumerInfo(consumer.stream, consumer.name)
+	if err != nil {
+		return err
+	}
+
+	consumer.Lock()
+	consumer.cfg = &info.Config
+	consumer.lastInfo = &info
+	consumer.Unlock()
+
+	return nil
+}
+
+func (m *Manager) loadConsumerInfo(s string, c string) (info api.ConsumerInfo, err error) {
+	return m.loadConsumerInfoWithContext(context.Background(), s, c)
+}
+
+func (m *Manager) loadConsumerInfoWithContext(ctx context.Context, s string, c string) (info api.ConsumerInfo, err error) {
+	var resp api.JSApiConsumerInfoResponse
+	err = m.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiConsumerInfoT, s, c), nil, &resp)
+	if err != nil {
+		return info, err
+	}
+
+	return *resp.ConsumerInfo, nil
+}
 
 // ConsumerDescription is a textual description of this consumer to provide additional context
 func ConsumerDescription(d string) ConsumerOption {
@@ -267,7 +925,7 @@ func DeliverySubject(s string) ConsumerOption {
 }
 
 // ConsumerName sets a name for the consumer, when creating a durable consumer use DurableName, using ConsumerName allows
-// for creating named ephemeral consumers, else a random name will be generated
+// for creating named ephemeral consumers, else a random name will be generated. See also NamedEphemeral()
 func ConsumerName(s string) ConsumerOption {
 	return func(o *api.ConsumerConfig) error {
 		if !IsValidName(s) {
@@ -279,6 +937,21 @@ func ConsumerName(s string) ConsumerOption {
 	}
 }
 
+// NamedEphemeral sets a name for an ephemeral consumer, unlike DurableName the consumer is removed by the server once
+// it is inactive, see InactiveThreshold(). Any durable name set by earlier options is cleared, it should not be
+// combined with DurableName which always names the consumer after the durable name
+func NamedEphemeral(name string) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if !IsValidName(name) {
+			return fmt.Errorf("%q is not a valid consumer name", name)
+		}
+
+		o.Durable = ""
+		o.Name = name
+		return nil
+	}
+}
+
 // DurableName is the name given to the consumer, when not set an ephemeral consumer is created
 func DurableName(s string) ConsumerOption {
 	return func(o *api.ConsumerConfig) error {
@@ -321,7 +994,8 @@ func DeliverAllAvailable() ConsumerOption {
 	}
 }
 
-// DeliverLastPerSubject delivers the last message for each subject in a wildcard stream based on the filter subjects of the consumer
+// DeliverLastPerSubject delivers the last message for each subject in a wildcard stream based on the filter subjects of the consumer,
+// at least one filter subject is required
 func DeliverLastPerSubject() ConsumerOption {
 	return func(o *api.ConsumerConfig) error {
 		resetDeliverPolicy(o)
@@ -353,283 +1027,1168 @@ func StartAtTimeDelta(d time.Duration) ConsumerOption {
 	return func(o *api.ConsumerConfig) error {
 		resetDeliverPolicy(o)
 
-		t := time.Now().UTC().Add(-1 * d)
-		o.DeliverPolicy = api.DeliverByStartTime
-		o.OptStartTime = &t
-		return nil
+		t := time.Now().UTC().Add(-1 * d)
+		o.DeliverPolicy = api.DeliverByStartTime
+		o.OptStartTime = &t
+		return nil
+	}
+}
+
+// CalendarUnit is a calendar period used to align consumer start times, see StartAtCalendarBoundary()
+type CalendarUnit int
+
+const (
+	// CalendarHour is the start of the current hour
+	CalendarHour CalendarUnit = iota
+	// CalendarDay is midnight of the current day
+	CalendarDay
+	// CalendarWeek is midnight of the Monday of the current week
+	CalendarWeek
+	// CalendarMonth is midnight of the first day of the current month
+	CalendarMonth
+)
+
+// StartAtCalendarBoundary starts consuming messages at the start of the current calendar unit in loc, or UTC
+// when loc is nil, for example CalendarDay starts at the most recent midnight. Boundaries are calculated in local
+// time so they remain correct across daylight saving changes
+func StartAtCalendarBoundary(unit CalendarUnit, loc *time.Location) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		start, err := calendarBoundary(time.Now(), unit, loc)
+		if err != nil {
+			return err
+		}
+
+		return StartAtTime(start)(o)
+	}
+}
+
+// calendarBoundary is the start of the calendar unit containing now in loc
+func calendarBoundary(now time.Time, unit CalendarUnit, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	t := now.In(loc)
+	y, m, d := t.Date()
+
+	switch unit {
+	case CalendarHour:
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond())), nil
+	case CalendarDay:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc), nil
+	case CalendarWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, loc), nil
+	case CalendarMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown calendar unit %d", unit)
+	}
+}
+
+// DeliverHeadersOnly configures the consumer to only deliver existing header and the `Nats-Msg-Size` header, no bodies
+func DeliverHeadersOnly() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.HeadersOnly = true
+		return nil
+	}
+}
+
+func resetDeliverPolicy(o *api.ConsumerConfig) {
+	o.DeliverPolicy = api.DeliverAll
+	o.OptStartSeq = 0
+	o.OptStartTime = nil
+}
+
+// AcknowledgeNone disables message acknowledgement
+func AcknowledgeNone() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.AckPolicy = api.AckNone
+		return nil
+	}
+}
+
+// AcknowledgeAll enables an acknowledgement mode where acknowledging message 100 will also ack the preceding messages
+func AcknowledgeAll() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.AckPolicy = api.AckAll
+		return nil
+	}
+}
+
+// AcknowledgeExplicit requires that every message received be acknowledged
+func AcknowledgeExplicit() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.AckPolicy = api.AckExplicit
+		return nil
+	}
+}
+
+// AckWait sets the time a delivered message might remain unacknowledged before redelivery is attempted
+func AckWait(t time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.AckWait = t
+		return nil
+	}
+}
+
+// MaxDeliveryAttempts is the number of times a message will be attempted to be delivered, -1 means unlimited
+func MaxDeliveryAttempts(n int) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		switch {
+		case n == 0:
+			return fmt.Errorf("configuration would prevent all deliveries")
+		case n < -1:
+			return fmt.Errorf("max delivery attempts must be -1 for unlimited or a positive number")
+		}
+
+		o.MaxDeliver = n
+		return nil
+	}
+}
+
+// UnlimitedDeliveryAttempts configures the consumer to attempt delivery of messages without limit
+func UnlimitedDeliveryAttempts() ConsumerOption {
+	return MaxDeliveryAttempts(-1)
+}
+
+// FilterStreamBySubject filters the messages in a wildcard stream to those matching a specific subject
+func FilterStreamBySubject(s ...string) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if len(s) == 1 {
+			o.FilterSubject = s[0]
+		} else {
+			o.FilterSubjects = append(o.FilterSubjects, s...)
+		}
+
+		return nil
+	}
+}
+
+// FilterFromStreamSubjects sets the filter subjects to the subjects of the stream as loaded when the option is applied,
+// when the stream has a single wildcard subject no filter is set since the consumer would receive all messages anyway
+func FilterFromStreamSubjects(mgr *Manager, stream string) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if mgr == nil {
+			return fmt.Errorf("a manager is required to load stream %s", stream)
+		}
+
+		if !IsValidName(stream) {
+			return fmt.Errorf("%q is not a valid stream name", stream)
+		}
+
+		nfo, err := mgr.loadStreamInfo(stream, nil)
+		if err != nil {
+			return fmt.Errorf("could not load stream %s: %w", stream, err)
+		}
+
+		subjects := nfo.Config.Subjects
+		if len(subjects) == 0 || (len(subjects) == 1 && strings.ContainsAny(subjects[0], "*>")) {
+			return nil
+		}
+
+		o.FilterSubject = ""
+		o.FilterSubjects = append([]string{}, subjects...)
+
+		return nil
+	}
+}
+
+// ReplayInstantly delivers messages to the consumer as fast as possible
+func ReplayInstantly() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.ReplayPolicy = api.ReplayInstant
+		return nil
+	}
+}
+
+// ReplayAsReceived delivers messages at the rate they were received at
+func ReplayAsReceived() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.ReplayPolicy = api.ReplayOriginal
+		return nil
+	}
+}
+
+// SamplePercent configures sampling of a subset of messages expressed as a percentage
+func SamplePercent(i int) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if i < 0 || i > 100 {
+			return fmt.Errorf("sample percent must be 0-100")
+		}
+
+		if i == 0 {
+			o.SampleFrequency = ""
+			return nil
+		}
+
+		o.SampleFrequency = fmt.Sprintf("%d%%", i)
+		return nil
+	}
+}
+
+// SampleFraction configures sampling of a percentage of acknowledgements given as a float, like SamplePercent() the
+// server only supports whole percentages so values with a fractional part like 0.1 are rejected
+func SampleFraction(f float64) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if math.IsNaN(f) || f < 0 || f > 100 {
+			return fmt.Errorf("sample fraction must be 0.0-100.0")
+		}
+
+		if f != math.Trunc(f) {
+			return fmt.Errorf("sample fraction must be a whole percentage, the server does not support sampling %v%%", f)
+		}
+
+		if f == 0 {
+			o.SampleFrequency = ""
+			return nil
+		}
+
+		o.SampleFrequency = fmt.Sprintf("%d%%", int(f))
+		return nil
+	}
+}
+
+// RateLimitBitsPerSecond limits message delivery to a rate in bits per second
+func RateLimitBitsPerSecond(bps uint64) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.RateLimit = bps
+		return nil
+	}
+}
+
+// MaxWaiting is the number of outstanding pulls that are allowed on any one consumer.  Pulls made that exceeds this limit are discarded.
+func MaxWaiting(pulls uint) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.MaxWaiting = int(pulls)
+		return nil
+	}
+}
+
+// MaxAckPending maximum number of messages without acknowledgement that can be outstanding, once this limit is reached message delivery will be suspended
+func MaxAckPending(pending uint) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.MaxAckPending = int(pending)
+		deleteConsumerMetadata(o, MaxAckPendingPerReplicaMetadataKey)
+		return nil
+	}
+}
+
+// IdleHeartbeat sets the time before an idle consumer will send a empty message with Status header 100 indicating the consumer is still alive
+func IdleHeartbeat(hb time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.Heartbeat = hb
+		return nil
+	}
+}
+
+// PushFlowControl enables flow control for push based consumers
+func PushFlowControl() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.FlowControl = true
+		return nil
+	}
+}
+
+// DeliverGroup when set will only deliver messages to subscriptions matching that group
+func DeliverGroup(g string) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.DeliverGroup = g
+		return nil
+	}
+}
+
+// MaxRequestMaxBytes sets the limit of max bytes a consumer my request
+func MaxRequestMaxBytes(max int) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.MaxRequestMaxBytes = max
+		return nil
+	}
+}
+
+// MaxRequestBatch is the largest batch that can be specified when doing pulls against the consumer
+func MaxRequestBatch(max uint) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.MaxRequestBatch = int(max)
+		return nil
+	}
+}
+
+// PullFleetLimits sizes a pull consumer for workers concurrent pullers each requesting batches of up to
+// batchPerWorker messages, MaxWaiting is raised to workers when lower and MaxRequestBatch is set to batchPerWorker.
+//
+// Pulls made once MaxWaiting pulls are outstanding are discarded, so workers should include headroom for
+// expected scaling. The NumWaiting reported in the consumer state shows how many pulls are outstanding and should
+// stay below MaxWaiting, it can not be checked when creating the consumer
+func PullFleetLimits(workers int, batchPerWorker int) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if workers < 1 {
+			return fmt.Errorf("workers must be positive")
+		}
+
+		if batchPerWorker < 1 {
+			return fmt.Errorf("batch per worker must be positive")
+		}
+
+		if o.MaxWaiting < workers {
+			o.MaxWaiting = workers
+		}
+		o.MaxRequestBatch = batchPerWorker
+
+		return nil
+	}
+}
+
+// MaxAckPendingPerReplica sets MaxAckPending to n multiplied by the number of consumer replicas, expressing the
+// acknowledgement pressure placed on each replica rather than an aggregate. n is kept in the consumer metadata under
+// MaxAckPendingPerReplicaMetadataKey so the max ack pending follows replica changes made when updating the consumer,
+// setting MaxAckPending() removes it.
+//
+// The replica count is the one set using ConsumerOverrideReplicas() or similar options, when none is set the
+// consumer inherits the stream replicas which are then loaded from the server when creating or updating the consumer
+func MaxAckPendingPerReplica(n uint) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if n == 0 {
+			return fmt.Errorf("max ack pending per replica must be positive")
+		}
+
+		setConsumerMetadata(o, MaxAckPendingPerReplicaMetadataKey, strconv.FormatUint(uint64(n), 10))
+
+		return nil
+	}
+}
+
+// maxAckPendingPerReplica is the max ack pending per replica set using MaxAckPendingPerReplica(), 0 when not set
+func maxAckPendingPerReplica(cfg *api.ConsumerConfig) (int, error) {
+	v, ok := cfg.Metadata[MaxAckPendingPerReplicaMetadataKey]
+	if !ok {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid max ack pending per replica %q in metadata key %s", v, MaxAckPendingPerReplicaMetadataKey)
+	}
+
+	return n, nil
+}
+
+// MaxRequestExpires is the longest pull request expire the server will allow
+func MaxRequestExpires(max time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if max != 0 && max < time.Millisecond {
+			return fmt.Errorf("must be larger than 1ms")
+		}
+
+		o.MaxRequestExpires = max
+		return nil
+	}
+}
+
+// MaxRequestBatchAtMost limits the largest batch a pull request can request to max unless a lower limit is already set,
+// unlike MaxRequestBatch it can only tighten the limit which allows options to be layered
+func MaxRequestBatchAtMost(max uint) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if max == 0 {
+			return nil
+		}
+
+		if o.MaxRequestBatch == 0 || o.MaxRequestBatch > int(max) {
+			o.MaxRequestBatch = int(max)
+		}
+
+		return nil
+	}
+}
+
+// MaxRequestExpiresAtMost limits the longest pull request expire to max unless a lower limit is already set, unlike
+// MaxRequestExpires it can only tighten the limit which allows options to be layered
+func MaxRequestExpiresAtMost(max time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if max == 0 {
+			return nil
+		}
+
+		if max < time.Millisecond {
+			return fmt.Errorf("must be larger than 1ms")
+		}
+
+		if o.MaxRequestExpires == 0 || o.MaxRequestExpires > max {
+			o.MaxRequestExpires = max
+		}
+
+		return nil
+	}
+}
+
+// InactiveThreshold is the idle time an ephemeral consumer allows before it is removed
+func InactiveThreshold(t time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if t < 0 {
+			return fmt.Errorf("inactive threshold must be positive")
+		}
+
+		o.InactiveThreshold = t
+		deleteConsumerMetadata(o, InactiveThresholdMultipleMetadataKey)
+
+		return nil
+	}
+}
+
+// InactiveThresholdMultiple sets the inactive threshold to n times the ack wait, it is calculated once all options
+// are applied so the final ack wait is used regardless of the order of options. n is kept in the consumer metadata
+// under InactiveThresholdMultipleMetadataKey so the threshold follows ack wait changes made when updating the
+// consumer, setting InactiveThreshold() removes it
+func InactiveThresholdMultiple(n float64) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if math.IsNaN(n) || math.IsInf(n, 0) || n <= 0 {
+			return fmt.Errorf("inactive threshold multiple must be positive")
+		}
+
+		setConsumerMetadata(o, InactiveThresholdMultipleMetadataKey, strconv.FormatFloat(n, 'f', -1, 64))
+
+		return nil
+	}
+}
+
+// resolveInactiveThresholdMultiple sets the inactive threshold of cfg to the multiple of its ack wait set using
+// InactiveThresholdMultiple()
+func resolveInactiveThresholdMultiple(cfg *api.ConsumerConfig) error {
+	v, ok := cfg.Metadata[InactiveThresholdMultipleMetadataKey]
+	if !ok {
+		return nil
+	}
+
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n <= 0 {
+		return fmt.Errorf("invalid inactive threshold multiple %q in metadata key %s", v, InactiveThresholdMultipleMetadataKey)
+	}
+
+	if cfg.AckWait <= 0 {
+		return fmt.Errorf("inactive threshold multiple requires an ack wait")
+	}
+
+	cfg.InactiveThreshold = time.Duration(float64(cfg.AckWait) * n)
+
+	return nil
+}
+
+// Guarantee is a message delivery guarantee, see DeliveryGuarantee()
+type Guarantee string
+
+const (
+	// AtMostOnce delivers every message once without acknowledgement, messages can be lost but are never redelivered
+	AtMostOnce Guarantee = "at_most_once"
+	// AtLeastOnce redelivers messages until they are acknowledged, messages are not lost but can be delivered more than once
+	AtLeastOnce Guarantee = "at_least_once"
+)
+
+// DeliveryGuarantee configures the acknowledgement and delivery settings needed for g, AtLeastOnce uses explicit
+// acknowledgement and an ack wait of 30 seconds unless set while AtMostOnce disables acknowledgement and redelivery.
+//
+// The guarantee is kept in the consumer metadata under DeliveryGuaranteeMetadataKey, creating or updating the
+// consumer fails when options applied later contradict the guarantee
+func DeliveryGuarantee(g Guarantee) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		switch g {
+		case AtMostOnce:
+			o.AckPolicy = api.AckNone
+			o.MaxDeliver = 1
+			o.BackOff = nil
+
+		case AtLeastOnce:
+			o.AckPolicy = api.AckExplicit
+			if o.AckWait == 0 {
+				o.AckWait = DefaultConsumer.AckWait
+			}
+			if o.MaxDeliver == 0 || o.MaxDeliver == 1 {
+				o.MaxDeliver = -1
+			}
+
+		default:
+			return fmt.Errorf("unknown delivery guarantee %q", g)
+		}
+
+		setConsumerMetadata(o, DeliveryGuaranteeMetadataKey, string(g))
+
+		return nil
+	}
+}
+
+// validateDeliveryGuarantee ensures the final configuration still provides the guarantee set using DeliveryGuarantee()
+func validateDeliveryGuarantee(cfg *api.ConsumerConfig) error {
+	g, ok := cfg.Metadata[DeliveryGuaranteeMetadataKey]
+	if !ok {
+		return nil
+	}
+
+	switch Guarantee(g) {
+	case AtMostOnce:
+		if cfg.AckPolicy != api.AckNone || cfg.MaxDeliver > 1 {
+			return fmt.Errorf("at most once delivery requires no acknowledgements and a single delivery attempt")
+		}
+
+	case AtLeastOnce:
+		if cfg.AckPolicy == api.AckNone || cfg.MaxDeliver == 1 {
+			return fmt.Errorf("at least once delivery requires acknowledgements and redelivery")
+		}
+
+	default:
+		return fmt.Errorf("unknown delivery guarantee %q in metadata key %s", g, DeliveryGuaranteeMetadataKey)
+	}
+
+	return nil
+}
+
+// BackoffIntervals sets a series of intervals by which retries will be attempted for this consumr
+func BackoffIntervals(i ...time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if len(i) == 0 {
+			return fmt.Errorf("at least one interval is required")
+		}
+
+		o.BackOff = i
+
+		return nil
+	}
+}
+
+// BackoffPolicy sets a consumer policy
+func BackoffPolicy(policy []time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.BackOff = policy
+		return nil
+	}
+}
+
+// ConsumerOverrideReplicas override the replica count inherited from the Stream with this value, even counts
+// above 1 are rejected as they do not improve the availability of the consumer
+func ConsumerOverrideReplicas(r int) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if r > 1 && r%2 == 0 {
+			return fmt.Errorf("consumer replicas should be an odd number, %d replicas tolerate no more failures than %d", r, r-1)
+		}
+
+		o.Replicas = r
+		return nil
+	}
+}
+
+// maxConsumerReplicas is the largest replica count supported by the server
+const maxConsumerReplicas = 5
+
+// ConsumerReplicasValidated sets the replica count like ConsumerOverrideReplicas but also rejects negative counts and
+// counts above the 5 replicas the server supports, 0 inherits the replica count of the stream
+func ConsumerReplicasValidated(r int) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		if r < 0 || r > maxConsumerReplicas {
+			return fmt.Errorf("consumer replicas must be between 0 and %d", maxConsumerReplicas)
+		}
+
+		return ConsumerOverrideReplicas(r)(o)
+	}
+}
+
+func ConsumerOverrideMemoryStorage() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		o.MemoryStorage = true
+		return nil
+	}
+}
+
+// LinearBackoffPolicy creates a backoff policy with linearly increasing steps between min and max
+func LinearBackoffPolicy(steps uint, min time.Duration, max time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		p, err := LinearBackoffPeriods(steps, min, max)
+		if err != nil {
+			return err
+		}
+
+		o.BackOff = p
+
+		return nil
+	}
+}
+
+// ExponentialBackoffPolicy creates a backoff policy with steps doubling from min until capped at max
+func ExponentialBackoffPolicy(steps uint, min time.Duration, max time.Duration) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		p, err := ExponentialBackoffPeriods(steps, min, max)
+		if err != nil {
+			return err
+		}
+
+		o.BackOff = p
+
+		return nil
+	}
+}
+
+// JitteredBackoffPolicy sets a backoff policy with every period in base perturbed by up to fraction, see BackoffPeriodsWithJitter()
+func JitteredBackoffPolicy(base []time.Duration, fraction float64, seed int64) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		p, err := BackoffPeriodsWithJitter(base, fraction, seed)
+		if err != nil {
+			return err
+		}
+
+		o.BackOff = p
+
+		return nil
+	}
+}
+
+// ConsumerMetadata sets the consumer metadata replacing any previously set, keys starting with _nats. are reserved
+// for the server and are rejected unless the Manager was created using WithReservedConsumerMetadata()
+func ConsumerMetadata(meta map[string]string) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		for k := range meta {
+			if len(k) == 0 {
+				return fmt.Errorf("invalid empty string key in metadata")
+			}
+		}
+
+		err := validateMetadataSize(meta, maxMetadataBytes)
+		if err != nil {
+			return err
+		}
+
+		o.Metadata = meta
+		return nil
+	}
+}
+
+// AddConsumerMetadata adds meta to the consumer metadata, replacing the values of existing keys
+func AddConsumerMetadata(meta map[string]string) ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		nmeta := make(map[string]string, len(o.Metadata)+len(meta))
+		for k, v := range o.Metadata {
+			nmeta[k] = v
+		}
+
+		for k, v := range meta {
+			if len(k) == 0 {
+				return fmt.Errorf("invalid empty string key in metadata")
+			}
+			nmeta[k] = v
+		}
+
+		err := validateMetadataSize(nmeta, maxMetadataBytes)
+		if err != nil {
+			return err
+		}
+
+		o.Metadata = nmeta
+		return nil
+	}
+}
+
+const (
+	// maxMetadataBytes is the maximum combined size of metadata keys and values accepted by the server
+	maxMetadataBytes = 128 * 1024
+	// serverMetadataPrefix is the prefix of metadata keys reserved for, and set by, the server
+	serverMetadataPrefix = "_nats."
+)
+
+// withoutServerMetadata is a copy of meta without the keys set by the server, nil when no keys remain
+func withoutServerMetadata(meta map[string]string) map[string]string {
+	var res map[string]string
+	for k, v := range meta {
+		if strings.HasPrefix(k, serverMetadataPrefix) {
+			continue
+		}
+
+		if res == nil {
+			res = make(map[string]string, len(meta))
+		}
+		res[k] = v
+	}
+
+	return res
+}
+
+// validateMetadataSize ensures the combined size of keys and values in meta, as calculated by the server, is at most limit
+func validateMetadataSize(meta map[string]string, limit int) error {
+	if limit <= 0 {
+		limit = maxMetadataBytes
+	}
+
+	var size int
+	for k, v := range meta {
+		size += len(k) + len(v)
+	}
+
+	if size > limit {
+		return fmt.Errorf("metadata size of %d bytes exceeds the limit of %d bytes", size, limit)
+	}
+
+	return nil
+}
+
+// validateReservedMetadata ensures cfg only sets reserved metadata keys found with the same value in dflt, unless allowed
+func validateReservedMetadata(dflt api.ConsumerConfig, cfg *api.ConsumerConfig, allow bool) error {
+	if allow {
+		return nil
+	}
+
+	for k, v := range cfg.Metadata {
+		if !strings.HasPrefix(k, serverMetadataPrefix) {
+			continue
+		}
+
+		if dv, ok := dflt.Metadata[k]; ok && dv == v {
+			continue
+		}
+
+		return fmt.Errorf("metadata key %q is reserved for the server", k)
+	}
+
+	return nil
+}
+
+const (
+	// FollowStreamReplicasMetadataKey is the metadata key that marks consumers managed by SyncConsumerReplicasToStream()
+	FollowStreamReplicasMetadataKey = "io.nats.jsm.follow_stream_replicas"
+	// MaxAckPendingPerReplicaMetadataKey is the metadata key holding the value set using MaxAckPendingPerReplica()
+	MaxAckPendingPerReplicaMetadataKey = "io.nats.jsm.max_ack_pending_per_replica"
+	// InactiveThresholdMultipleMetadataKey is the metadata key holding the value set using InactiveThresholdMultiple()
+	InactiveThresholdMultipleMetadataKey = "io.nats.jsm.inactive_threshold_multiple"
+	// DeliveryGuaranteeMetadataKey is the metadata key holding the guarantee set using DeliveryGuarantee()
+	DeliveryGuaranteeMetadataKey = "io.nats.jsm.delivery_guarantee"
+	// PriorityMinPendingMetadataKey is the metadata key holding the min pending threshold set using PriorityOverflow()
+	PriorityMinPendingMetadataKey = "io.nats.jsm.priority_min_pending"
+	// PriorityMinAckPendingMetadataKey is the metadata key holding the min ack pending threshold set using PriorityOverflow()
+	PriorityMinAckPendingMetadataKey = "io.nats.jsm.priority_min_ack_pending"
+	// NamedEphemeralMetadataKey is the metadata key that marks ephemeral consumers created with a chosen name
+	NamedEphemeralMetadataKey = "io.nats.jsm.named_ephemeral"
+)
+
+// FollowStreamReplicas marks the consumer to have its replica count kept in line with the stream by SyncConsumerReplicasToStream()
+func FollowStreamReplicas() ConsumerOption {
+	return func(o *api.ConsumerConfig) error {
+		setConsumerMetadata(o, FollowStreamReplicasMetadataKey, "true")
+
+		return nil
+	}
+}
+
+// setConsumerMetadata sets key to value in a copy of the metadata of o
+func setConsumerMetadata(o *api.ConsumerConfig, key string, value string) {
+	meta := make(map[string]string, len(o.Metadata)+1)
+	for k, v := range o.Metadata {
+		meta[k] = v
+	}
+	meta[key] = value
+	o.Metadata = meta
+}
+
+// deleteConsumerMetadata removes key from a copy of the metadata of o
+func deleteConsumerMetadata(o *api.ConsumerConfig, key string) {
+	if _, ok := o.Metadata[key]; !ok {
+		return
+	}
+
+	meta := make(map[string]string, len(o.Metadata))
+	for k, v := range o.Metadata {
+		if k != key {
+			meta[k] = v
+		}
+	}
+	o.Metadata = meta
+}
+
+// resolveConsumerMetadataSettings applies and validates the settings options keep in the metadata of cfg that depend
+// on the final configuration
+func resolveConsumerMetadataSettings(cfg *api.ConsumerConfig) error {
+	err := resolveInactiveThresholdMultiple(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = validateDeliveryGuarantee(cfg)
+	if err != nil {
+		return err
+	}
+
+	perReplica, err := maxAckPendingPerReplica(cfg)
+	if err != nil {
+		return err
 	}
+	if perReplica > 0 && cfg.Replicas > 0 {
+		cfg.MaxAckPending = perReplica * cfg.Replicas
+	}
+
+	return validatePriorityOverflow(cfg)
 }
 
-// DeliverHeadersOnly configures the consumer to only deliver existing header and the `Nats-Msg-Size` header, no bodies
-func DeliverHeadersOnly() ConsumerOption {
+// FromConfig copies all settings from cfg into the configuration being built, options that follow can override them.
+// Metadata keys set by the server are not copied so configurations loaded from the server can be used
+func FromConfig(cfg api.ConsumerConfig) ConsumerOption {
 	return func(o *api.ConsumerConfig) error {
-		o.HeadersOnly = true
+		*o = copyConsumerConfig(cfg)
+		o.Metadata = withoutServerMetadata(o.Metadata)
+
 		return nil
 	}
 }
 
-func resetDeliverPolicy(o *api.ConsumerConfig) {
-	o.DeliverPolicy = api.DeliverAll
-	o.OptStartSeq = 0
-	o.OptStartTime = nil
-}
+// copyConsumerConfig creates a copy of cfg that does not share any maps, slices or pointers with it
+func copyConsumerConfig(cfg api.ConsumerConfig) api.ConsumerConfig {
+	ncfg := cfg
 
-// AcknowledgeNone disables message acknowledgement
-func AcknowledgeNone() ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.AckPolicy = api.AckNone
-		return nil
+	if cfg.FilterSubjects != nil {
+		ncfg.FilterSubjects = append([]string{}, cfg.FilterSubjects...)
 	}
-}
 
-// AcknowledgeAll enables an acknowledgement mode where acknowledging message 100 will also ack the preceding messages
-func AcknowledgeAll() ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.AckPolicy = api.AckAll
-		return nil
+	if cfg.BackOff != nil {
+		ncfg.BackOff = append([]time.Duration{}, cfg.BackOff...)
 	}
-}
 
-// AcknowledgeExplicit requires that every message received be acknowledged
-func AcknowledgeExplicit() ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.AckPolicy = api.AckExplicit
-		return nil
+	if cfg.PriorityGroups != nil {
+		ncfg.PriorityGroups = append([]string{}, cfg.PriorityGroups...)
 	}
-}
 
-// AckWait sets the time a delivered message might remain unacknowledged before redelivery is attempted
-func AckWait(t time.Duration) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.AckWait = t
-		return nil
+	if cfg.OptStartTime != nil {
+		t := *cfg.OptStartTime
+		ncfg.OptStartTime = &t
 	}
-}
 
-// MaxDeliveryAttempts is the number of times a message will be attempted to be delivered
-func MaxDeliveryAttempts(n int) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		if n == 0 {
-			return fmt.Errorf("configuration would prevent all deliveries")
-		}
-		o.MaxDeliver = n
-		return nil
+	if cfg.PauseUntil != nil {
+		t := *cfg.PauseUntil
+		ncfg.PauseUntil = &t
 	}
-}
 
-// FilterStreamBySubject filters the messages in a wildcard stream to those matching a specific subject
-func FilterStreamBySubject(s ...string) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		if len(s) == 1 {
-			o.FilterSubject = s[0]
-		} else {
-			o.FilterSubjects = append(o.FilterSubjects, s...)
+	if cfg.Metadata != nil {
+		ncfg.Metadata = make(map[string]string, len(cfg.Metadata))
+		for k, v := range cfg.Metadata {
+			ncfg.Metadata[k] = v
 		}
-
-		return nil
 	}
+
+	return ncfg
 }
 
-// ReplayInstantly delivers messages to the consumer as fast as possible
-func ReplayInstantly() ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.ReplayPolicy = api.ReplayInstant
-		return nil
+// consumerConfigDifferences compares the server stored settings of live and desired and describes every differing
+// field, when onlySet is true fields with a zero value in desired are not compared allowing server defaults to be ignored
+func consumerConfigDifferences(live api.ConsumerConfig, desired api.ConsumerConfig, onlySet bool) []string {
+	var diff []string
+
+	for _, d := range consumerConfigFieldDifferences(live, desired, onlySet) {
+		diff = append(diff, fmt.Sprintf("%s: %s != %s", d.field, d.live, d.desired))
 	}
+
+	return diff
 }
 
-// ReplayAsReceived delivers messages at the rate they were received at
-func ReplayAsReceived() ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.ReplayPolicy = api.ReplayOriginal
-		return nil
-	}
+type configFieldDifference struct {
+	field   string
+	live    string
+	desired string
 }
 
-// SamplePercent configures sampling of a subset of messages expressed as a percentage
-func SamplePercent(i int) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		if i < 0 || i > 100 {
-			return fmt.Errorf("sample percent must be 0-100")
+func consumerConfigFieldDifferences(live api.ConsumerConfig, desired api.ConsumerConfig, onlySet bool) []configFieldDifference {
+	var diff []configFieldDifference
+
+	lv := reflect.ValueOf(live)
+	dv := reflect.ValueOf(desired)
+	t := lv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		lf := lv.Field(i)
+		df := dv.Field(i)
+
+		if onlySet && df.IsZero() {
+			continue
 		}
 
-		if i == 0 {
-			o.SampleFrequency = ""
-			return nil
+		if reflect.DeepEqual(lf.Interface(), df.Interface()) {
+			continue
 		}
 
-		o.SampleFrequency = fmt.Sprintf("%d%%", i)
-		return nil
+		diff = append(diff, configFieldDifference{field.Name, configValueString(lf), configValueString(df)})
 	}
-}
 
-// RateLimitBitsPerSecond limits message delivery to a rate in bits per second
-func RateLimitBitsPerSecond(bps uint64) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.RateLimit = bps
-		return nil
-	}
+	return diff
 }
 
-// MaxWaiting is the number of outstanding pulls that are allowed on any one consumer.  Pulls made that exceeds this limit are discarded.
-func MaxWaiting(pulls uint) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.MaxWaiting = int(pulls)
-		return nil
+// ConsumerMutableFields are the names of api.ConsumerConfig fields the server allows to be changed on existing consumers
+func ConsumerMutableFields() []string {
+	return []string{
+		"Description",
+		"AckWait",
+		"MaxDeliver",
+		"SampleFrequency",
+		"MaxAckPending",
+		"MaxWaiting",
+		"HeadersOnly",
+		"Metadata",
+		"BackOff",
+		"FilterSubject",
+		"FilterSubjects",
+		"RateLimit",
+		"InactiveThreshold",
+		"Replicas",
+		"MaxRequestBatch",
+		"MaxRequestExpires",
+		"MaxRequestMaxBytes",
 	}
 }
 
-// MaxAckPending maximum number of messages without acknowledgement that can be outstanding, once this limit is reached message delivery will be suspended
-func MaxAckPending(pending uint) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.MaxAckPending = int(pending)
-		return nil
+// UpdatableDiff compares desired to the consumer configuration and splits the names of the differing fields into
+// those that can be changed using UpdateConfiguration() and those that require the consumer to be recreated. Only
+// fields set in desired are compared so server defaults are not reported, metadata keys set by the server and the
+// form used to set a single filter subject are not considered differences
+func (c *Consumer) UpdatableDiff(desired api.ConsumerConfig) (updatable []string, immutable []string, err error) {
+	if desired.Durable != "" && desired.Durable != c.DurableName() {
+		return nil, nil, fmt.Errorf("desired configuration is for consumer %s not %s", desired.Durable, c.name)
 	}
-}
 
-// IdleHeartbeat sets the time before an idle consumer will send a empty message with Status header 100 indicating the consumer is still alive
-func IdleHeartbeat(hb time.Duration) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.Heartbeat = hb
-		return nil
+	if desired.Name != "" && desired.Name != c.name {
+		return nil, nil, fmt.Errorf("desired configuration is for consumer %s not %s", desired.Name, c.name)
 	}
-}
 
-// PushFlowControl enables flow control for push based consumers
-func PushFlowControl() ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.FlowControl = true
-		return nil
+	mutable := make(map[string]bool)
+	for _, f := range ConsumerMutableFields() {
+		mutable[f] = true
 	}
-}
 
-// DeliverGroup when set will only deliver messages to subscriptions matching that group
-func DeliverGroup(g string) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.DeliverGroup = g
-		return nil
+	for _, d := range consumerConfigFieldDifferences(comparableConsumerConfig(c.Configuration()), comparableConsumerConfig(desired), true) {
+		if mutable[d.field] {
+			updatable = append(updatable, d.field)
+		} else {
+			immutable = append(immutable, d.field)
+		}
 	}
+
+	return updatable, immutable, nil
 }
 
-// MaxRequestMaxBytes sets the limit of max bytes a consumer my request
-func MaxRequestMaxBytes(max int) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.MaxRequestMaxBytes = max
-		return nil
+func configValueString(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "unset"
+		}
+		v = v.Elem()
 	}
+
+	return fmt.Sprintf("%v", v.Interface())
 }
 
-// MaxRequestBatch is the largest batch that can be specified when doing pulls against the consumer
-func MaxRequestBatch(max uint) ConsumerOption {
+// PriorityOverflow configures the consumer for the Overflow priority policy and sets the thresholds sent with pull
+// requests made by this package, messages are only delivered once the consumer has at least minPending messages
+// pending or minAckPending messages awaiting acknowledgement. The consumer must have priority groups configured.
+//
+// The server does not store the thresholds, they are kept in the consumer metadata under
+// PriorityMinPendingMetadataKey and PriorityMinAckPendingMetadataKey so every Consumer loaded for it uses them
+func PriorityOverflow(minPending uint64, minAckPending uint64) ConsumerOption {
 	return func(o *api.ConsumerConfig) error {
-		o.MaxRequestBatch = int(max)
+		if minPending == 0 && minAckPending == 0 {
+			return fmt.Errorf("at least one priority overflow threshold is required")
+		}
+
+		o.PriorityPolicy = api.PriorityOverflow
+
+		for key, threshold := range map[string]uint64{PriorityMinPendingMetadataKey: minPending, PriorityMinAckPendingMetadataKey: minAckPending} {
+			if threshold == 0 {
+				deleteConsumerMetadata(o, key)
+			} else {
+				setConsumerMetadata(o, key, strconv.FormatUint(threshold, 10))
+			}
+		}
+
 		return nil
 	}
 }
 
-// MaxRequestExpires is the longest pull request expire the server will allow
-func MaxRequestExpires(max time.Duration) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		if max != 0 && max < time.Millisecond {
-			return fmt.Errorf("must be larger than 1ms")
+// priorityOverflowThresholds are the thresholds set using PriorityOverflow()
+func priorityOverflowThresholds(cfg *api.ConsumerConfig) (minPending uint64, minAckPending uint64, err error) {
+	parse := func(key string) (uint64, error) {
+		v, ok := cfg.Metadata[key]
+		if !ok {
+			return 0, nil
 		}
 
-		o.MaxRequestExpires = max
-		return nil
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid priority overflow threshold %q in metadata key %s", v, key)
+		}
+
+		return n, nil
+	}
+
+	minPending, err = parse(PriorityMinPendingMetadataKey)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	minAckPending, err = parse(PriorityMinAckPendingMetadataKey)
+	if err != nil {
+		return 0, 0, err
 	}
+
+	return minPending, minAckPending, nil
 }
 
-// InactiveThreshold is the idle time an ephemeral consumer allows before it is removed
-func InactiveThreshold(t time.Duration) ConsumerOption {
+// ConsumerPriorityGroups sets the priority groups pull requests are made against, a priority policy must also be set
+// using ConsumerPriorityPolicy() or PriorityOverflow()
+func ConsumerPriorityGroups(groups ...string) ConsumerOption {
 	return func(o *api.ConsumerConfig) error {
-		if t < 0 {
-			return fmt.Errorf("inactive threshold must be positive")
+		if len(groups) == 0 {
+			return fmt.Errorf("at least one priority group is required")
 		}
 
-		o.InactiveThreshold = t
+		for _, group := range groups {
+			if !priorityGroupRe.MatchString(group) {
+				return fmt.Errorf("%q is not a valid priority group name", group)
+			}
+		}
+
+		o.PriorityGroups = append([]string{}, groups...)
 
 		return nil
 	}
 }
 
-// BackoffIntervals sets a series of intervals by which retries will be attempted for this consumr
-func BackoffIntervals(i ...time.Duration) ConsumerOption {
+// ConsumerPriorityPolicy sets the policy used to select which pull requests receive messages, consumers using a
+// policy other than api.PriorityNone require priority groups, see ConsumerPriorityGroups()
+func ConsumerPriorityPolicy(policy api.PriorityPolicy) ConsumerOption {
 	return func(o *api.ConsumerConfig) error {
-		if len(i) == 0 {
-			return fmt.Errorf("at least one interval is required")
+		switch policy {
+		case api.PriorityNone, api.PriorityOverflow, api.PriorityPinnedClient:
+		default:
+			return fmt.Errorf("unknown priority policy %d", policy)
 		}
 
-		o.BackOff = i
+		o.PriorityPolicy = policy
 
 		return nil
 	}
 }
 
-// BackoffPolicy sets a consumer policy
-func BackoffPolicy(policy []time.Duration) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.BackOff = policy
-		return nil
-	}
+// UpdateConfiguration updates the configuration of a durable or named ephemeral consumer, no request is made when
+// the options do not change it. At present the description, ack wait, max deliver, sample frequency, max ack pending, max waiting and header only settings can be changed,
+// changing other settings fails with an error wrapping ErrConsumerExistsDifferentConfig
+func (c *Consumer) UpdateConfiguration(opts ...ConsumerOption) error {
+	_, err := c.UpdateConfigurationIfChanged(opts...)
+	return err
 }
 
-// ConsumerOverrideReplicas override the replica count inherited from the Stream with this value
-func ConsumerOverrideReplicas(r int) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.Replicas = r
-		return nil
+// UpdateConfigurationIfChanged updates the consumer configuration like UpdateConfiguration but reports if the
+// configuration stored on the server changed, when opts result in the current configuration no request is made
+func (c *Consumer) UpdateConfigurationIfChanged(opts ...ConsumerOption) (changed bool, err error) {
+	if c.IsEphemeral() && !c.IsNamedEphemeral() {
+		return false, fmt.Errorf("only durable and named ephemeral consumers can be updated")
 	}
-}
 
-func ConsumerOverrideMemoryStorage() ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		o.MemoryStorage = true
-		return nil
+	current := c.Configuration()
+
+	ncfg, err := c.mgr.buildConsumerConfiguration(current, opts...)
+	if err != nil {
+		return false, err
 	}
-}
 
-// LinearBackoffPolicy creates a backoff policy with linearly increasing steps between min and max
-func LinearBackoffPolicy(steps uint, min time.Duration, max time.Duration) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		p, err := LinearBackoffPeriods(steps, min, max)
+	// resolved before comparing as the max ack pending is only known once the stream replicas are loaded
+	err = c.mgr.resolveMaxAckPendingPerReplica(context.Background(), c.stream, ncfg)
+	if err != nil {
+		return false, err
+	}
+
+	changed = len(consumerConfigFieldDifferences(normalizedConsumerConfig(current), normalizedConsumerConfig(*ncfg), false)) > 0
+	if changed {
+		_, err = c.mgr.createConsumerFromConfig(context.Background(), c.stream, ncfg)
 		if err != nil {
-			return err
+			return false, err
 		}
+	}
 
-		o.BackOff = p
+	requested := copyConsumerConfig(*ncfg)
 
-		return nil
+	c.Lock()
+	c.requested = &requested
+	c.Unlock()
+
+	if !changed {
+		return false, nil
 	}
+
+	return true, c.Reset()
 }
 
-func ConsumerMetadata(meta map[string]string) ConsumerOption {
-	return func(o *api.ConsumerConfig) error {
-		for k := range meta {
-			if len(k) == 0 {
-				return fmt.Errorf("invalid empty string key in metadata")
-			}
-		}
+// ConfigDifference describes the settings that would change when updating the consumer using opts without updating
+// it, the list is empty when opts do not change the configuration. The order of filter subjects is not significant
+func (c *Consumer) ConfigDifference(opts ...ConsumerOption) ([]string, error) {
+	current := c.Configuration()
 
-		o.Metadata = meta
-		return nil
+	ncfg, err := c.mgr.buildConsumerConfiguration(current, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.mgr.resolveMaxAckPendingPerReplica(context.Background(), c.stream, ncfg)
+	if err != nil {
+		return nil, err
 	}
+
+	return consumerConfigDifferences(comparableConsumerConfig(current), comparableConsumerConfig(*ncfg), false), nil
 }
 
-// UpdateConfiguration updates the consumer configuration
-// At present the description, ack wait, max deliver, sample frequency, max ack pending, max waiting and header only settings can be changed
-func (c *Consumer) UpdateConfiguration(opts ...ConsumerOption) error {
-	if !c.IsDurable() {
-		return fmt.Errorf("only durable consumers can be updated")
+// comparableConsumerConfig is cfg normalized using normalizedConsumerConfig() with the metadata keys set by the server
+// and the named ephemeral marker removed and the filter subjects sorted, comparing these configurations only reports
+// settings a client can change
+func comparableConsumerConfig(cfg api.ConsumerConfig) api.ConsumerConfig {
+	cfg = normalizedConsumerConfig(cfg)
+	cfg.Metadata = withoutServerMetadata(cfg.Metadata)
+	// withoutServerMetadata() returns a copy so the marker can be removed in place
+	delete(cfg.Metadata, NamedEphemeralMetadataKey)
+	if len(cfg.Metadata) == 0 {
+		cfg.Metadata = nil
+	}
+	if cfg.FilterSubjects != nil {
+		cfg.FilterSubjects = append([]string{}, cfg.FilterSubjects...)
+		sort.Strings(cfg.FilterSubjects)
+	}
+
+	return cfg
+}
+
+// normalizedConsumerConfig is cfg with equivalent representations used by the server made identical, a single
+// filter subject is set in FilterSubject and empty lists and maps are nil
+func normalizedConsumerConfig(cfg api.ConsumerConfig) api.ConsumerConfig {
+	if len(cfg.FilterSubjects) == 1 {
+		cfg.FilterSubject = cfg.FilterSubjects[0]
+		cfg.FilterSubjects = nil
+	}
+	if len(cfg.FilterSubjects) == 0 {
+		cfg.FilterSubjects = nil
+	}
+	if len(cfg.BackOff) == 0 {
+		cfg.BackOff = nil
 	}
+	if len(cfg.Metadata) == 0 {
+		cfg.Metadata = nil
+	}
+	if len(cfg.PriorityGroups) == 0 {
+		cfg.PriorityGroups = nil
+	}
+
+	return cfg
+}
 
-	ncfg, err := NewConsumerConfiguration(*c.cfg, opts...)
+// ResetState resets the delivery position and acknowledgement state of the consumer by deleting it and creating it
+// again using the same configuration, delivery restarts according to its deliver policy. All progress is lost,
+// should creating the consumer fail after it was deleted the error indicates that the consumer no longer exists
+func (c *Consumer) ResetState() error {
+	cfg := c.Configuration()
+
+	err := c.Delete()
 	if err != nil {
-		return err
+		return fmt.Errorf("deleting consumer %s > %s failed: %w", c.stream, c.name, err)
 	}
 
-	_, err = c.mgr.NewConsumerFromDefault(c.stream, *ncfg)
+	_, err = c.mgr.NewConsumerFromDefault(c.stream, cfg)
 	if err != nil {
-		return err
+		return fmt.Errorf("consumer %s > %s was deleted but creating it again failed: %w", c.stream, c.name, err)
 	}
 
 	return c.Reset()
@@ -673,6 +2232,42 @@ func NextSubject(stream string, consumer string) (string, error) {
 	return fmt.Sprintf(api.JSApiRequestNextT, stream, consumer), nil
 }
 
+// AckSubjectFor is the subject acknowledgements for msg should be published to, the server does not support
+// overriding the acknowledgement subject of a consumer so this is always the reply subject of the message after
+// verifying it was delivered by this consumer
+func (c *Consumer) AckSubjectFor(msg *nats.Msg) (string, error) {
+	if msg == nil {
+		return "", fmt.Errorf("message is required")
+	}
+
+	nfo, err := ParseJSMsgMetadata(msg)
+	if err != nil {
+		return "", err
+	}
+
+	if nfo.Stream() != c.stream || nfo.Consumer() != c.name {
+		return "", fmt.Errorf("message was delivered by %s > %s not %s > %s", nfo.Stream(), nfo.Consumer(), c.stream, c.name)
+	}
+
+	return msg.Reply, nil
+}
+
+// OriginStreamFor is the stream and sequence a message received from the consumer was originally stored at, for
+// messages sourced from other streams this is the origin stream and sequence, for other messages it is the stream
+// and sequence of the consumer stream. This uniquely identifies messages consumed from streams sourcing many streams
+func (c *Consumer) OriginStreamFor(msg *nats.Msg) (stream string, seq uint64, err error) {
+	nfo, err := ParseJSMsgMetadata(msg)
+	if err != nil {
+		return "", 0, fmt.Errorf("message does not have JetStream metadata: %w", err)
+	}
+
+	if nfo.OriginStream() != "" {
+		return nfo.OriginStream(), nfo.OriginSequence(), nil
+	}
+
+	return nfo.Stream(), nfo.StreamSequence(), nil
+}
+
 // AckSampleSubject is the subject used to publish ack samples to
 func (c *Consumer) AckSampleSubject() string {
 	if c.SampleFrequency() == "" {
@@ -692,6 +2287,30 @@ func (c *Consumer) MetricSubject() string {
 	return api.JSMetricPrefix + ".CONSUMER.*." + c.StreamName() + "." + c.name
 }
 
+// PushSubscribeOptions is the delivery subject of a push consumer and nats.go subscription options matching its
+// configuration. The subject is used with nc.Subscribe or, when the consumer has a deliver group, nc.QueueSubscribe
+// using DeliverGroup() as queue. The options bind a JetStream subscription made using js.Subscribe or
+// js.QueueSubscribe to this consumer with acknowledgements handled by the caller, these are given the filter subject
+// of the consumer rather than the delivery subject
+func (c *Consumer) PushSubscribeOptions() (subject string, subOpts []nats.SubOpt, err error) {
+	if !c.IsPushMode() {
+		return "", nil, fmt.Errorf("consumer %s > %s is not a push consumer", c.stream, c.name)
+	}
+
+	subOpts = []nats.SubOpt{nats.Bind(c.stream, c.name)}
+
+	switch c.AckPolicy() {
+	case api.AckNone:
+		subOpts = append(subOpts, nats.AckNone())
+	case api.AckAll:
+		subOpts = append(subOpts, nats.AckAll(), nats.ManualAck())
+	default:
+		subOpts = append(subOpts, nats.AckExplicit(), nats.ManualAck())
+	}
+
+	return c.DeliverySubject(), subOpts, nil
+}
+
 // NextMsg requests the next message from the server with the manager timeout
 func (m *Manager) NextMsg(stream string, consumer string) (*nats.Msg, error) {
 	if !m.nc.Opts.UseOldRequestStyle {
@@ -783,6 +2402,29 @@ func (c *Consumer) AcknowledgedFloor() (api.SequenceInfo, error) {
 	return info.AckFloor, nil
 }
 
+// DeliveredConsumerSeq reports the consumer sequence of the last delivered message. Consumer sequences count
+// deliveries made by the consumer, including redeliveries, without gaps while stream sequences, as found in
+// DeliveredState().Stream, skip messages not matching the consumer filter
+func (c *Consumer) DeliveredConsumerSeq() (uint64, error) {
+	info, err := c.State()
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Delivered.Consumer, nil
+}
+
+// AckFloorConsumerSeq reports the highest consumer sequence up to which all deliveries were acknowledged, see
+// DeliveredConsumerSeq() for how consumer and stream sequences differ
+func (c *Consumer) AckFloorConsumerSeq() (uint64, error) {
+	info, err := c.State()
+	if err != nil {
+		return 0, err
+	}
+
+	return info.AckFloor.Consumer, nil
+}
+
 // PendingAcknowledgement reports the number of messages sent but not yet acknowledged
 func (c *Consumer) PendingAcknowledgement() (int, error) {
 	info, err := c.State()
@@ -803,6 +2445,144 @@ func (c *Consumer) PendingMessages() (uint64, error) {
 	return info.NumPending, nil
 }
 
+// FetchLastNPerSubject reads the last n messages stored in the stream for every subject matching the consumer
+// filter subjects, oldest first. JetStream consumers can only deliver the last message per subject, see
+// DeliverLastPerSubject(), so the messages are read from the stream using the message get API without affecting
+// the consumer state.
+//
+// The message get API can not search backwards so each earlier message is found using a binary search over the
+// stream sequences before the later one, about n*log2(last sequence) requests are made per subject
+func (c *Consumer) FetchLastNPerSubject(ctx context.Context, n int) (map[string][]*api.StoredMsg, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("n must be at least 1")
+	}
+
+	cfg := c.Configuration()
+	filters := consumerFilterSubjects(&cfg)
+	if len(filters) == 0 {
+		filters = []string{">"}
+	}
+
+	counts := map[string]uint64{}
+	for _, filter := range filters {
+		subjects, err := c.mgr.StreamContainedSubjects(c.stream, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		for subject, count := range subjects {
+			counts[subject] = count
+		}
+	}
+
+	result := make(map[string][]*api.StoredMsg, len(counts))
+
+	for subject, count := range counts {
+		want := n
+		if count < uint64(n) {
+			want = int(count)
+		}
+
+		var resp api.JSApiMsgGetResponse
+		err := c.mgr.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiMsgGetT, c.stream), api.JSApiMsgGetRequest{LastFor: subject}, &resp)
+		if IsNatsError(err, errCodeNoMessageFound) {
+			// servers can fail to load the last message for a subject once the message that was last was deleted,
+			// the search also finds nothing when the messages were removed after the subjects were listed
+			resp.Message, err = c.previousMsgForSubject(ctx, subject, math.MaxUint64)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Message == nil {
+			continue
+		}
+
+		msgs := make([]*api.StoredMsg, want)
+		msgs[want-1] = resp.Message
+
+		i := want - 1
+		for ; i > 0; i-- {
+			prev, err := c.previousMsgForSubject(ctx, subject, msgs[i].Sequence)
+			if err != nil {
+				return nil, err
+			}
+			if prev == nil {
+				break
+			}
+
+			msgs[i-1] = prev
+		}
+
+		result[subject] = msgs[i:]
+	}
+
+	return result, nil
+}
+
+// errCodeNoMessageFound is the error code the message get API responds with whenever the stream fails to load the
+// requested message, including when no message for the subject is stored at or after the requested sequence
+const errCodeNoMessageFound = 10037
+
+// previousMsgForSubject finds the last message stored for subject before sequence before, nil when there is none.
+// A get for the next message from a sequence lands before before only when the previous message is at or after
+// that sequence, which allows a binary search for it
+func (c *Consumer) previousMsgForSubject(ctx context.Context, subject string, before uint64) (*api.StoredMsg, error) {
+	var found *api.StoredMsg
+
+	lo, hi := uint64(1), before-1
+	for lo <= hi && hi > 0 {
+		mid := lo + (hi-lo)/2
+
+		var resp api.JSApiMsgGetResponse
+		err := c.mgr.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiMsgGetT, c.stream), api.JSApiMsgGetRequest{Seq: mid, NextFor: subject}, &resp)
+		switch {
+		case IsNatsError(err, errCodeNoMessageFound):
+			hi = mid - 1
+		case err != nil:
+			return nil, err
+		case resp.Message.Sequence >= before:
+			hi = mid - 1
+		default:
+			found = resp.Message
+			lo = resp.Message.Sequence + 1
+		}
+	}
+
+	return found, nil
+}
+
+// ConsumerLag is how many stream sequences the acknowledgement floor of standby trails that of primary, a negative
+// value means standby is ahead of primary. Both consumers must be on the same stream and have the same filter subjects
+func ConsumerLag(primary *Consumer, standby *Consumer) (int64, error) {
+	if primary == nil || standby == nil {
+		return 0, fmt.Errorf("primary and standby consumers are required")
+	}
+
+	if primary.stream != standby.stream {
+		return 0, fmt.Errorf("consumers %s > %s and %s > %s are not on the same stream", primary.stream, primary.name, standby.stream, standby.name)
+	}
+
+	pnfo, err := primary.State()
+	if err != nil {
+		return 0, err
+	}
+
+	snfo, err := standby.State()
+	if err != nil {
+		return 0, err
+	}
+
+	pf := consumerFilterSubjects(&pnfo.Config)
+	sf := consumerFilterSubjects(&snfo.Config)
+	sort.Strings(pf)
+	sort.Strings(sf)
+	if !reflect.DeepEqual(pf, sf) {
+		return 0, fmt.Errorf("consumers %s and %s have different filter subjects", primary.name, standby.name)
+	}
+
+	return int64(pnfo.AckFloor.Stream) - int64(snfo.AckFloor.Stream), nil
+}
+
 // WaitingClientPulls is the number of clients that have outstanding pull requests against this consumer
 func (c *Consumer) WaitingClientPulls() (int, error) {
 	info, err := c.State()
@@ -838,7 +2618,17 @@ func (c *Consumer) LatestState() (api.ConsumerInfo, error) {
 
 // State loads a snapshot of consumer state including delivery counts, retries and more
 func (c *Consumer) State() (api.ConsumerInfo, error) {
-	s, err := c.mgr.loadConsumerInfo(c.stream, c.name)
+	return c.stateWithContext(context.Background())
+}
+
+// Info loads a snapshot of consumer state like State() but does not replace the information cached for LatestState(),
+// use this to probe the consumer without affecting other users of the same Consumer
+func (c *Consumer) Info() (api.ConsumerInfo, error) {
+	return c.mgr.loadConsumerInfoWithContext(context.Background(), c.stream, c.name)
+}
+
+func (c *Consumer) stateWithContext(ctx context.Context) (api.ConsumerInfo, error) {
+	s, err := c.mgr.loadConsumerInfoWithContext(ctx, c.stream, c.name)
 	if err != nil {
 		return api.ConsumerInfo{}, err
 	}
@@ -850,16 +2640,246 @@ func (c *Consumer) State() (api.ConsumerInfo, error) {
 	return s, nil
 }
 
+// ReplayProgress reports how far delivery has progressed through the stream as a fraction between 0 and 1, mainly
+// useful for consumers replaying messages using ReplayAsReceived().
+//
+// Progress is calculated using the last delivered stream sequence relative to the stream first and last sequences,
+// 1.0 is returned once delivery has caught up with the stream head
+func (c *Consumer) ReplayProgress(ctx context.Context) (fraction float64, err error) {
+	nfo, err := c.stateWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	snfo, err := c.mgr.loadStreamInfoWithContext(ctx, c.stream, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	first := snfo.State.FirstSeq
+	last := snfo.State.LastSeq
+	delivered := nfo.Delivered.Stream
+
+	switch {
+	case nfo.NumPending == 0, last == 0, delivered >= last:
+		return 1, nil
+	case delivered < first:
+		return 0, nil
+	}
+
+	return float64(delivered-first+1) / float64(last-first+1), nil
+}
+
+// WaitForAssignment waits until the consumer is able to report its state, this can be used after creating consumers in a
+// cluster to ensure they are assigned and have elected a leader before being used. Transient errors like missing
+// leaders are retried until ctx is cancelled while other errors are returned immediately
+func (c *Consumer) WaitForAssignment(ctx context.Context) error {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		rctx, cancel := context.WithTimeout(ctx, c.mgr.timeout)
+		_, err := c.stateWithContext(rctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if !isRetryableError(err) {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// StateFromLeader loads the consumer state ensuring it was reported while the consumer had an elected leader, in
+// clustered setups responses without a leader, or transient errors like timeouts, are retried until ctx is cancelled.
+// When ctx has no deadline the manager timeout is used, ErrNoLeader is returned when no leader reported the state
+// in time. Each attempt is limited to the manager timeout so an unresponsive server does not use up all of ctx
+func (c *Consumer) StateFromLeader(ctx context.Context) (api.ConsumerInfo, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.mgr.timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	var lastErr error
+
+	for {
+		rctx, cancel := context.WithTimeout(ctx, c.mgr.timeout)
+		nfo, err := c.stateWithContext(rctx)
+		cancel()
+		switch {
+		case err == nil && (nfo.Cluster == nil || nfo.Cluster.Leader != ""):
+			return nfo, nil
+		case err == nil:
+			lastErr = fmt.Errorf("no leader reported in cluster %q", nfo.Cluster.Name)
+		case ctx.Err() == nil && !isRetryableError(err):
+			return api.ConsumerInfo{}, err
+		case ctx.Err() == nil:
+			lastErr = err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = ctx.Err()
+			}
+			return api.ConsumerInfo{}, fmt.Errorf("%w: %s > %s: %v", ErrNoLeader, c.stream, c.name, lastErr)
+		}
+	}
+}
+
+const (
+	// stateRetryInitialDelay is the delay before the first retry made by StateRetry, it doubles after each attempt
+	stateRetryInitialDelay = 100 * time.Millisecond
+	// stateRetryMaxDelay is the longest delay between attempts made by StateRetry
+	stateRetryMaxDelay = 5 * time.Second
+)
+
+// StateRetry loads the consumer state making up to maxAttempts attempts, transient errors like timeouts, no
+// responders or an unavailable leader are retried with an exponential backoff starting at 100ms and capped at 5s.
+// Other errors, like the consumer not being found, are returned immediately as is an error once ctx is cancelled
+func (c *Consumer) StateRetry(ctx context.Context, maxAttempts int) (api.ConsumerInfo, error) {
+	if maxAttempts < 1 {
+		return api.ConsumerInfo{}, fmt.Errorf("at least one attempt is required")
+	}
+
+	delay := stateRetryInitialDelay
+
+	for attempt := 1; ; attempt++ {
+		rctx, cancel := context.WithTimeout(ctx, c.mgr.timeout)
+		nfo, err := c.stateWithContext(rctx)
+		cancel()
+		switch {
+		case err == nil:
+			return nfo, nil
+		case ctx.Err() != nil:
+			return api.ConsumerInfo{}, ctx.Err()
+		case !isRetryableError(err), attempt >= maxAttempts:
+			return api.ConsumerInfo{}, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return api.ConsumerInfo{}, ctx.Err()
+		}
+
+		delay *= 2
+		if delay > stateRetryMaxDelay {
+			delay = stateRetryMaxDelay
+		}
+	}
+}
+
+// EffectiveSubjects is the set of subjects the consumer reads from its stream, combining the consumer filters with the
+// subjects of the stream. Filters narrower than a stream subject are returned as is, stream subjects narrower than a
+// filter are returned in place of the filter and partially overlapping wildcards are refined to their intersection.
+// Subjects covered by another returned subject are omitted.
+//
+// Streams without subjects, like mirrors and streams with only sources, can hold any subject so the filters, or
+// > when there are none, are returned
+func (c *Consumer) EffectiveSubjects(ctx context.Context) ([]string, error) {
+	nfo, err := c.mgr.loadStreamInfoWithContext(ctx, c.stream, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := c.Configuration()
+	filters := consumerFilterSubjects(&cfg)
+	if len(filters) == 0 {
+		filters = []string{">"}
+	}
+
+	if len(nfo.Config.Subjects) == 0 {
+		return filters, nil
+	}
+
+	var candidates []string
+	for _, filter := range filters {
+		for _, subject := range nfo.Config.Subjects {
+			intersection, ok := subjectIntersection(filter, subject)
+			if ok {
+				candidates = append(candidates, intersection)
+			}
+		}
+	}
+
+	var effective []string
+	for i, candidate := range candidates {
+		covered := false
+		for j, other := range candidates {
+			if i == j || !SubjectIsSubsetMatch(candidate, other) {
+				continue
+			}
+
+			// identical subjects cover each other so only the first is kept
+			if candidate != other || j < i {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			effective = append(effective, candidate)
+		}
+	}
+
+	return effective, nil
+}
+
 // Configuration is the Consumer configuration
 func (c *Consumer) Configuration() (config api.ConsumerConfig) {
 	return *c.cfg
 }
 
+// RequestedConfig is the configuration that was sent to the server when this handle created or last updated the
+// consumer, ok is false for consumers that were loaded rather than created
+func (c *Consumer) RequestedConfig() (config api.ConsumerConfig, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.requested == nil {
+		return api.ConsumerConfig{}, false
+	}
+
+	return copyConsumerConfig(*c.requested), true
+}
+
+// ServerConfig is the configuration as normalized and stored by the server including any defaults it applied
+func (c *Consumer) ServerConfig() api.ConsumerConfig {
+	c.Lock()
+	defer c.Unlock()
+
+	return copyConsumerConfig(*c.cfg)
+}
+
 // Delete deletes the Consumer, after this the Consumer object should be disposed
 func (c *Consumer) Delete() (err error) {
+	return c.DeleteContext(context.Background())
+}
+
+// DeleteContext deletes the Consumer waiting up to the deadline of ctx, or the manager timeout when ctx has no
+// deadline, after this the Consumer object should be disposed. When ctx is cancelled ctx.Err() is returned
+func (c *Consumer) DeleteContext(ctx context.Context) (err error) {
 	var resp api.JSApiConsumerDeleteResponse
-	err = c.mgr.jsonRequest(fmt.Sprintf(api.JSApiConsumerDeleteT, c.StreamName(), c.Name()), nil, &resp)
+	err = c.mgr.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiConsumerDeleteT, c.StreamName(), c.Name()), nil, &resp)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return err
 	}
 
@@ -885,6 +2905,227 @@ func (c *Consumer) LeaderStepDown() error {
 	return nil
 }
 
+// stepDownAttempts is the number of leader elections StepDownToPreferred() requests before giving up
+const stepDownAttempts = 10
+
+// StepDownToPreferred moves leadership of a clustered consumer to preferredServer, which has to be one of its
+// replicas. A leader step down naming the preferred server is requested, servers that do not support preferred
+// placement are sent a plain step down and elect a random replica so the step down is repeated up to 10 times until
+// the preferred server is leader.
+// An error is returned when leadership did not move to the preferred server before ctx is cancelled
+func (c *Consumer) StepDownToPreferred(ctx context.Context, preferredServer string) error {
+	if preferredServer == "" {
+		return fmt.Errorf("preferred server is required")
+	}
+
+	nfo, err := c.stateWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if nfo.Cluster == nil {
+		return fmt.Errorf("consumer %s > %s is not clustered", c.stream, c.name)
+	}
+
+	if nfo.Cluster.Leader == preferredServer {
+		return nil
+	}
+
+	known := false
+	for _, peer := range nfo.Cluster.Replicas {
+		if peer.Name == preferredServer {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("%s is not a replica of consumer %s > %s", preferredServer, c.stream, c.name)
+	}
+
+	leader := nfo.Cluster.Leader
+	var req any = api.JSApiLeaderStepDownRequest{Placement: &api.Placement{Preferred: preferredServer}}
+
+	for i := 0; i < stepDownAttempts; i++ {
+		var resp api.JSApiConsumerLeaderStepDownResponse
+		err = c.mgr.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiConsumerLeaderStepDownT, c.stream, c.name), req, &resp)
+		if req != nil && IsNatsError(err, 10003) {
+			// servers without preferred placement support reject a step down request with a body
+			req = nil
+			err = c.mgr.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiConsumerLeaderStepDownT, c.stream, c.name), req, &resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		leader, err = c.waitForNewLeader(ctx, leader)
+		if err != nil {
+			return err
+		}
+
+		if leader == preferredServer {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("leadership of consumer %s > %s did not move to %s after %d attempts, current leader is %s", c.stream, c.name, preferredServer, stepDownAttempts, leader)
+}
+
+// waitForNewLeader waits until the consumer reports a leader other than previous and returns it
+func (c *Consumer) waitForNewLeader(ctx context.Context, previous string) (string, error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		rctx, cancel := context.WithTimeout(ctx, c.mgr.timeout)
+		nfo, err := c.stateWithContext(rctx)
+		cancel()
+		switch {
+		case err == nil && nfo.Cluster != nil && nfo.Cluster.Leader != "" && nfo.Cluster.Leader != previous:
+			return nfo.Cluster.Leader, nil
+		case err != nil && !isRetryableError(err):
+			return "", err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return "", fmt.Errorf("no new leader was elected for consumer %s > %s: %w", c.stream, c.name, ctx.Err())
+		}
+	}
+}
+
+// SequenceRange is an inclusive range of stream sequences
+type SequenceRange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+// StreamGaps reports ranges of sequences in the stream holding the consumer that no longer hold messages, these
+// are messages removed from the head of the stream by its retention policy, deleted messages and messages lost
+// to corruption. Removed messages do not retain their subjects so the ranges are not limited to the consumer filter.
+//
+// Streams with more deleted messages than the server reports in its detailed state produce an error, use
+// Stream.DetectGaps() to find gaps in those
+func (c *Consumer) StreamGaps(ctx context.Context) ([]SequenceRange, error) {
+	nfo, err := c.mgr.loadStreamInfoWithContext(ctx, c.stream, &api.JSApiStreamInfoRequest{DeletedDetails: true})
+	if err != nil {
+		return nil, err
+	}
+
+	state := nfo.State
+	if len(state.Deleted) < state.NumDeleted {
+		return nil, fmt.Errorf("stream %s has %d deleted messages but only %d were reported", c.stream, state.NumDeleted, len(state.Deleted))
+	}
+
+	var gaps []SequenceRange
+	if state.FirstSeq > 1 {
+		gaps = append(gaps, SequenceRange{Start: 1, End: state.FirstSeq - 1})
+	}
+
+	seqs := append([]uint64{}, state.Deleted...)
+	if state.Lost != nil {
+		seqs = append(seqs, state.Lost.Msgs...)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	for _, seq := range seqs {
+		if seq < state.FirstSeq {
+			continue
+		}
+
+		last := len(gaps) - 1
+		switch {
+		case last >= 0 && seq <= gaps[last].End:
+		case last >= 0 && seq == gaps[last].End+1:
+			gaps[last].End = seq
+		default:
+			gaps = append(gaps, SequenceRange{Start: seq, End: seq})
+		}
+	}
+
+	return gaps, nil
+}
+
+// StreamRetention loads the retention policy of the stream holding the consumer
+func (c *Consumer) StreamRetention(ctx context.Context) (api.RetentionPolicy, error) {
+	nfo, err := c.mgr.loadStreamInfoWithContext(ctx, c.stream, nil)
+	if err != nil {
+		return api.LimitsPolicy, err
+	}
+
+	return nfo.Config.Retention, nil
+}
+
+// IsWorkQueueConsumer determines if the consumer is on a work queue stream where acknowledging a message removes it
+func (c *Consumer) IsWorkQueueConsumer(ctx context.Context) (bool, error) {
+	retention, err := c.StreamRetention(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return retention == api.WorkQueuePolicy, nil
+}
+
+// EffectiveAckWait is how long the server waits for an acknowledgement of a message on its deliveryCount delivery
+// before redelivering it, this is the matching BackOff entry, or the last one for later deliveries, or AckWait
+// when no BackOff is set
+func (c *Consumer) EffectiveAckWait(deliveryCount int) time.Duration {
+	if len(c.cfg.BackOff) == 0 {
+		return c.cfg.AckWait
+	}
+
+	idx := deliveryCount - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(c.cfg.BackOff) {
+		idx = len(c.cfg.BackOff) - 1
+	}
+
+	return c.cfg.BackOff[idx]
+}
+
+// AckWaitRemaining is how long remains before the server redelivers msg if it is not acknowledged, 0 when the
+// ack wait already passed. The message metadata only holds the time the message was stored in the stream so
+// received should be the local time msg was received, the ack wait is measured from then
+func (c *Consumer) AckWaitRemaining(msg *nats.Msg, received time.Time) (time.Duration, error) {
+	if msg == nil {
+		return 0, fmt.Errorf("message is required")
+	}
+
+	if received.IsZero() {
+		return 0, fmt.Errorf("receive time is required")
+	}
+
+	nfo, err := ParseJSMsgMetadata(msg)
+	if err != nil {
+		return 0, fmt.Errorf("message does not have JetStream metadata: %w", err)
+	}
+
+	if nfo.Stream() != c.stream || nfo.Consumer() != c.name {
+		return 0, fmt.Errorf("message was delivered by %s > %s not %s > %s", nfo.Stream(), nfo.Consumer(), c.stream, c.name)
+	}
+
+	remaining := time.Until(received.Add(c.EffectiveAckWait(nfo.Delivered())))
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// IsNamedEphemeral indicates the consumer is ephemeral and was given a name, see NamedEphemeral(). Named ephemeral
+// consumers are marked in their metadata under NamedEphemeralMetadataKey when created by this package
+func (c *Consumer) IsNamedEphemeral() bool {
+	return c.IsEphemeral() && c.cfg.Metadata[NamedEphemeralMetadataKey] == "true"
+}
+
+// PriorityGroups are the groups pull requests can be made against when using a priority policy
+func (c *Consumer) PriorityGroups() []string { return c.cfg.PriorityGroups }
+
+// PriorityPolicy is the policy used to select which pull requests receive messages
+func (c *Consumer) PriorityPolicy() api.PriorityPolicy { return c.cfg.PriorityPolicy }
+
 func (c *Consumer) Name() string                     { return c.name }
 func (c *Consumer) IsSampled() bool                  { return c.SampleFrequency() != "" }
 func (c *Consumer) IsPullMode() bool                 { return c.cfg.DeliverSubject == "" }
@@ -901,11 +3142,20 @@ func (c *Consumer) DeliverPolicy() api.DeliverPolicy { return c.cfg.DeliverPolic
 func (c *Consumer) AckPolicy() api.AckPolicy         { return c.cfg.AckPolicy }
 func (c *Consumer) AckWait() time.Duration           { return c.cfg.AckWait }
 func (c *Consumer) MaxDeliver() int                  { return c.cfg.MaxDeliver }
+func (c *Consumer) IsUnlimitedDelivery() bool        { return c.cfg.MaxDeliver < 1 }
 func (c *Consumer) Backoff() []time.Duration         { return c.cfg.BackOff }
 func (c *Consumer) FilterSubject() string            { return c.cfg.FilterSubject }
 func (c *Consumer) FilterSubjects() []string         { return c.cfg.FilterSubjects }
 func (c *Consumer) ReplayPolicy() api.ReplayPolicy   { return c.cfg.ReplayPolicy }
 func (c *Consumer) SampleFrequency() string          { return c.cfg.SampleFrequency }
+func (c *Consumer) SamplePercentage() float64 {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(c.cfg.SampleFrequency), "%"), 64)
+	if err != nil || f < 0 || f > 100 {
+		return 0
+	}
+
+	return f
+}
 func (c *Consumer) RateLimit() uint64                { return c.cfg.RateLimit }
 func (c *Consumer) MaxAckPending() int               { return c.cfg.MaxAckPending }
 func (c *Consumer) FlowControl() bool                { return c.cfg.FlowControl }
@@ -919,6 +3169,9 @@ func (c *Consumer) InactiveThreshold() time.Duration { return c.cfg.InactiveThre
 func (c *Consumer) Replicas() int                    { return c.cfg.Replicas }
 func (c *Consumer) Metadata() map[string]string      { return c.cfg.Metadata }
 func (c *Consumer) MemoryStorage() bool              { return c.cfg.MemoryStorage }
+func (c *Consumer) FollowsStreamReplicas() bool {
+	return c.cfg.Metadata[FollowStreamReplicasMetadataKey] == "true"
+}
 func (c *Consumer) StartTime() time.Time {
 	if c.cfg.OptStartTime == nil {
 		return time.Time{}
@@ -14,8 +14,15 @@
 package jsm_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -537,6 +544,152 @@ func TestConsumer_AcknowledgedState(t *testing.T) {
 	}
 }
 
+func TestConsumer_ConsumerSequences(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for _, subj := range []string{"ORDERS.shipped", "ORDERS.new"} {
+		_, err := nc.Request(subj, []byte(subj), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	durable, err := mgr.NewConsumer("ORDERS", jsm.DurableName("D"), jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 2; i++ {
+		m, err := durable.NextMsg()
+		checkErr(t, err, "next failed")
+		checkErr(t, m.Respond(nil), "ack failed")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	delivered, err := durable.DeliveredConsumerSeq()
+	checkErr(t, err, "state failed")
+	floor, err := durable.AckFloorConsumerSeq()
+	checkErr(t, err, "state failed")
+	state, err := durable.DeliveredState()
+	checkErr(t, err, "state failed")
+
+	if delivered != 2 || floor != 2 || state.Stream != 3 {
+		t.Fatalf("expected consumer sequences 2 and stream sequence 3 got %d, %d and %d", delivered, floor, state.Stream)
+	}
+}
+
+func TestConsumer_FetchLastNPerSubject(t *testing.T) {
+	srv, nc, stream, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for _, body := range []string{"order 2", "order 3", "order 4"} {
+		_, err := nc.Request("ORDERS.new", []byte(body), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+	_, err := nc.Request("ORDERS.shipped", []byte("shipped 1"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("LAST"), jsm.FilterStreamBySubject("ORDERS.>"))
+	checkErr(t, err, "create failed")
+
+	_, err = c.FetchLastNPerSubject(context.Background(), 0)
+	if err == nil {
+		t.Fatalf("expected invalid n error")
+	}
+
+	last, err := c.FetchLastNPerSubject(context.Background(), 2)
+	checkErr(t, err, "fetch failed")
+
+	bodies := map[string][]string{}
+	for subject, msgs := range last {
+		for _, msg := range msgs {
+			bodies[subject] = append(bodies[subject], string(msg.Data))
+		}
+	}
+
+	expected := map[string][]string{"ORDERS.new": {"order 3", "order 4"}, "ORDERS.shipped": {"shipped 1"}}
+	if !cmp.Equal(bodies, expected) {
+		t.Fatalf("invalid messages: %s", cmp.Diff(expected, bodies))
+	}
+
+	pending, err := c.PendingMessages()
+	checkErr(t, err, "state failed")
+	if pending != 5 {
+		t.Fatalf("expected consumer state to be unchanged, got %d pending", pending)
+	}
+
+	// interleaved subjects with gaps from deleted messages
+	for i := 1; i <= 40; i++ {
+		_, err := nc.Request(fmt.Sprintf("ORDERS.%d", i%3), []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+	// stream sequences 35 and 44 hold messages 30 and 39 on ORDERS.0, deleting the last message makes the server
+	// respond with a no message found error for the last message of the subject
+	checkErr(t, stream.DeleteMessage(35), "delete failed")
+	checkErr(t, stream.DeleteMessage(44), "delete failed")
+
+	c, err = mgr.NewConsumer("ORDERS", jsm.DurableName("LAST0"), jsm.FilterStreamBySubject("ORDERS.0"))
+	checkErr(t, err, "create failed")
+
+	last, err = c.FetchLastNPerSubject(context.Background(), 4)
+	checkErr(t, err, "fetch failed")
+
+	var got []string
+	for _, msg := range last["ORDERS.0"] {
+		got = append(got, string(msg.Data))
+	}
+	if len(last) != 1 || !cmp.Equal(got, []string{"24", "27", "33", "36"}) {
+		t.Fatalf("invalid messages: %v", got)
+	}
+
+	// subjects with fewer than n messages return all of them
+	last, err = c.FetchLastNPerSubject(context.Background(), 20)
+	checkErr(t, err, "fetch failed")
+
+	got = nil
+	for _, msg := range last["ORDERS.0"] {
+		got = append(got, string(msg.Data))
+	}
+	if !cmp.Equal(got, []string{"3", "6", "9", "12", "15", "18", "21", "24", "27", "33", "36"}) {
+		t.Fatalf("invalid messages: %v", got)
+	}
+}
+
+func TestConsumer_OriginStreamFor(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := mgr.NewStream("AGG", jsm.Sources(&api.StreamSource{Name: "ORDERS"}), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	orders, err := mgr.NewConsumer("ORDERS", jsm.DurableName("ORDERS"))
+	checkErr(t, err, "create failed")
+	agg, err := mgr.NewConsumer("AGG", jsm.DurableName("AGG"))
+	checkErr(t, err, "create failed")
+
+	msg, err := orders.NextMsg()
+	checkErr(t, err, "next failed")
+	stream, seq, err := orders.OriginStreamFor(msg)
+	checkErr(t, err, "origin failed")
+	if stream != "ORDERS" || seq != 1 {
+		t.Fatalf("expected ORDERS 1 got %s %d", stream, seq)
+	}
+
+	msg, err = agg.NextMsg()
+	checkErr(t, err, "next failed")
+	stream, seq, err = agg.OriginStreamFor(msg)
+	checkErr(t, err, "origin failed")
+	if stream != "ORDERS" || seq != 1 {
+		t.Fatalf("expected ORDERS 1 got %s %d", stream, seq)
+	}
+
+	_, _, err = agg.OriginStreamFor(nats.NewMsg("x"))
+	if err == nil {
+		t.Fatalf("expected metadata error")
+	}
+}
+
 func TestConsumer_Configuration(t *testing.T) {
 	srv, nc, _, mgr := setupConsumerTest(t)
 	defer srv.Shutdown()
@@ -572,6 +725,116 @@ func TestConsumer_Delete(t *testing.T) {
 	}
 }
 
+func TestConsumer_DeleteContext(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("D"))
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = c.DeleteContext(ctx)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	checkErr(t, c.DeleteContext(ctx), "delete failed")
+
+	known, err := mgr.IsKnownConsumer("ORDERS", "D")
+	checkErr(t, err, "known failed")
+	if known {
+		t.Fatalf("expected consumer to be deleted")
+	}
+}
+
+func TestNewConsumerFromDefaultWithContext(t *testing.T) {
+	srv, nc, _ := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err := nc.Subscribe("FAKE.CONSUMER.CREATE.ORDERS.C1", func(msg *nats.Msg) {
+		// slower than the manager timeout, as creates can be on a busy cluster
+		time.Sleep(time.Second)
+		msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_create_response","stream_name":"ORDERS","name":"C1","config":{"durable_name":"C1","name":"C1","ack_policy":"explicit","deliver_policy":"all","replay_policy":"instant"}}`))
+	})
+	checkErr(t, err, "subscribe failed")
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"), jsm.WithTimeout(500*time.Millisecond))
+	checkErr(t, err, "manager failed")
+
+	_, err = fake.NewConsumerFromDefault("ORDERS", jsm.DefaultConsumer, jsm.DurableName("C1"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected manager timeout got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := fake.NewConsumerFromDefaultWithContext(ctx, "ORDERS", jsm.DefaultConsumer, jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+	if c.Name() != "C1" || c.StreamName() != "ORDERS" {
+		t.Fatalf("unexpected consumer %s > %s", c.StreamName(), c.Name())
+	}
+
+	cctx, ccancel := context.WithCancel(context.Background())
+	ccancel()
+
+	_, err = fake.NewConsumerFromDefaultWithContext(cctx, "ORDERS", jsm.DefaultConsumer, jsm.DurableName("C1"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error got %v", err)
+	}
+}
+
+func TestNewConsumer_DeliverLastPerSubject(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for _, subj := range []string{"ORDERS.a", "ORDERS.a", "ORDERS.b", "ORDERS.b", "ORDERS.c"} {
+		_, err := nc.Request(subj, []byte(subj), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	_, err := mgr.NewConsumer("ORDERS", jsm.DeliverLastPerSubject())
+	if err == nil || err.Error() != "deliver last per subject requires at least one filter subject" {
+		t.Fatalf("expected filter error got %v", err)
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DeliverLastPerSubject(), jsm.FilterStreamBySubject("ORDERS.a", "ORDERS.b"))
+	checkErr(t, err, "create failed")
+
+	pending, err := c.PendingMessages()
+	checkErr(t, err, "pending failed")
+	if pending != 2 {
+		t.Fatalf("expected 2 pending messages got %d", pending)
+	}
+
+	c, err = mgr.NewConsumer("ORDERS", jsm.DeliverLastPerSubject(), func(o *api.ConsumerConfig) error {
+		o.FilterSubjects = []string{"ORDERS.c"}
+		return nil
+	})
+	checkErr(t, err, "create failed")
+
+	pending, err = c.PendingMessages()
+	checkErr(t, err, "pending failed")
+	if pending != 1 || !cmp.Equal(consumerFilters(c), []string{"ORDERS.c"}) {
+		t.Fatalf("expected 1 pending message for ORDERS.c got %d: %+v", pending, c.Configuration())
+	}
+}
+
+func consumerFilters(c *jsm.Consumer) []string {
+	cfg := c.Configuration()
+	if cfg.FilterSubject != "" {
+		return []string{cfg.FilterSubject}
+	}
+
+	return cfg.FilterSubjects
+}
+
 func TestConsumer_IsDurable(t *testing.T) {
 	srv, nc, _, mgr := setupConsumerTest(t)
 	defer srv.Shutdown()
@@ -676,292 +939,1967 @@ func TestConsumer_UpdateConfiguration(t *testing.T) {
 	}
 }
 
-func testConsumerConfig() *api.ConsumerConfig {
-	return &api.ConsumerConfig{
-		AckWait:       0,
-		AckPolicy:     api.AckExplicit,
-		MaxDeliver:    -1,
-		ReplayPolicy:  api.ReplayInstant,
-		OptStartSeq:   0,
-		OptStartTime:  nil,
-		DeliverPolicy: api.DeliverAll,
-	}
-}
-func TestAckWait(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.AckWait(time.Hour)(cfg)
-	if cfg.AckWait != time.Hour {
-		t.Fatalf("expected 1 hour got %v", cfg.AckWait)
-	}
-}
+func TestConsumer_UpdateNamedEphemeral(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
 
-func TestAcknowledgeAll(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.AcknowledgeAll()(cfg)
-	if cfg.AckPolicy != api.AckAll {
-		t.Fatalf("expected AckAll got %s", cfg.AckPolicy)
-	}
-}
+	c, err := mgr.NewConsumer("ORDERS", jsm.NamedEphemeral("NAMED"), jsm.InactiveThreshold(time.Minute), jsm.AckWait(time.Minute))
+	checkErr(t, err, "create failed")
 
-func TestAcknowledgeExplicit(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.AcknowledgeExplicit()(cfg)
-	if cfg.AckPolicy != api.AckExplicit {
-		t.Fatalf("expected AckExplicit got %s", cfg.AckPolicy)
+	checkErr(t, c.UpdateConfiguration(jsm.AckWait(2*time.Minute)), "update failed")
+	if c.AckWait() != 2*time.Minute || c.Name() != "NAMED" || c.IsDurable() {
+		t.Fatalf("expected ack wait to be updated: %+v", c.Configuration())
 	}
-}
 
-func TestAcknowledgeNone(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.AcknowledgeNone()(cfg)
-	if cfg.AckPolicy != api.AckNone {
-		t.Fatalf("expected AckNone got %s", cfg.AckPolicy)
+	nfo, err := c.State()
+	checkErr(t, err, "state failed")
+	if nfo.Config.AckWait != 2*time.Minute {
+		t.Fatalf("expected server ack wait to be updated: %v", nfo.Config.AckWait)
 	}
-}
 
-func TestDeliverAllAvailable(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.DeliverAllAvailable()(cfg)
-	if cfg.DeliverPolicy != api.DeliverAll {
-		t.Fatal("expected DeliverAll")
+	anon, err := mgr.NewConsumer("ORDERS")
+	checkErr(t, err, "create failed")
+	err = anon.UpdateConfiguration(jsm.AckWait(2 * time.Minute))
+	if err == nil || err.Error() != "only durable and named ephemeral consumers can be updated" {
+		t.Fatalf("expected anonymous ephemeral error got %v", err)
 	}
 }
 
-func TestDeliverySubject(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.DeliverySubject("out")(cfg)
-	if cfg.DeliverSubject != "out" {
-		t.Fatalf("expected 'out' got %q", cfg.DeliverSubject)
-	}
-}
+func TestConsumer_ConfigDifference(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
 
-func TestDurableName(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.DurableName("test")(cfg)
-	if cfg.Durable != "test" {
-		t.Fatalf("expected 'test' got %q", cfg.Durable)
-	}
-}
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.FilterStreamBySubject("ORDERS.a", "ORDERS.b"), jsm.AckWait(time.Minute))
+	checkErr(t, err, "create failed")
 
-func TestFilterStreamBySubject(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.FilterStreamBySubject("test")(cfg)
-	if cfg.FilterSubject != "test" {
-		t.Fatalf("expected 'test' got %q", cfg.FilterSubject)
+	reordered := func(o *api.ConsumerConfig) error {
+		o.FilterSubjects = []string{"ORDERS.b", "ORDERS.a"}
+		return nil
 	}
 
-	if len(cfg.FilterSubjects) > 0 {
-		t.Fatalf("expected no filter subjects got %v", cfg.FilterSubjects)
+	diff, err := c.ConfigDifference(reordered, jsm.AckWait(time.Minute))
+	checkErr(t, err, "difference failed")
+	if len(diff) != 0 {
+		t.Fatalf("expected no differences got %v", diff)
 	}
 
-	cfg = testConsumerConfig()
-	jsm.FilterStreamBySubject("test", "1", "2", "3")(cfg)
-	if cfg.FilterSubject != "" {
-		t.Fatalf("expected '' got %q", cfg.FilterSubject)
-	}
-	if !cmp.Equal([]string{"test", "1", "2", "3"}, cfg.FilterSubjects) {
-		t.Fatalf("expected [test, 1, 2, 3] got %v", cfg.FilterSubjects)
+	diff, err = c.ConfigDifference(jsm.AckWait(2*time.Minute), jsm.ConsumerDescription("orders"))
+	checkErr(t, err, "difference failed")
+	expected := []string{"Description:  != orders", "AckWait: 1m0s != 2m0s"}
+	if !cmp.Equal(diff, expected) {
+		t.Fatalf("invalid differences: %s", cmp.Diff(expected, diff))
 	}
-}
 
-func TestMaxDeliveryAttempts(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.MaxDeliveryAttempts(10)(cfg)
-	if cfg.MaxDeliver != 10 {
-		t.Fatalf("expected 10 got %q", cfg.MaxDeliver)
+	if c.AckWait() != time.Minute || !cmp.Equal(c.FilterSubjects(), []string{"ORDERS.a", "ORDERS.b"}) {
+		t.Fatalf("expected the consumer to be unchanged: %+v", c.Configuration())
 	}
 
-	err := jsm.MaxDeliveryAttempts(0)
+	_, err = c.ConfigDifference(jsm.MaxDeliveryAttempts(0))
 	if err == nil {
-		t.Fatalf("expected 0 deliveries to fail")
+		t.Fatalf("expected option error")
 	}
 }
 
-func TestReplayAsReceived(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.ReplayAsReceived()(cfg)
-	if cfg.ReplayPolicy != api.ReplayOriginal {
-		t.Fatalf("expected ReplayOriginal got %s", cfg.ReplayPolicy)
-	}
-}
+func TestConsumer_UpdateConfigurationIfChanged(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
 
-func TestReplayInstantly(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.ReplayInstantly()(cfg)
-	if cfg.ReplayPolicy != api.ReplayInstant {
-		t.Fatalf("expected ReplayInstant got %s", cfg.ReplayPolicy)
-	}
-}
+	update, err := mgr.NewConsumer("ORDERS", jsm.DurableName("UPDATE"), jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "create failed")
 
-func TestSamplePercent(t *testing.T) {
-	cfg := testConsumerConfig()
-	err := jsm.SamplePercent(200)(cfg)
-	if err == nil {
-		t.Fatal("impossible percent didnt error")
+	changed, err := update.UpdateConfigurationIfChanged(jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "update failed")
+	if changed {
+		t.Fatalf("expected no change")
 	}
 
-	err = jsm.SamplePercent(-1)(cfg)
-	if err == nil {
-		t.Fatal("impossible percent didnt error")
+	changed, err = update.UpdateConfigurationIfChanged(jsm.ConsumerDescription("updated"))
+	checkErr(t, err, "update failed")
+	if !changed || update.Description() != "updated" {
+		t.Fatalf("expected updated description got %q", update.Description())
 	}
 
-	err = jsm.SamplePercent(0)(cfg)
-	checkErr(t, err, "good percent errored")
-	if cfg.SampleFrequency != "" {
-		t.Fatal("expected empty string")
+	changed, err = update.UpdateConfigurationIfChanged(jsm.ConsumerDescription("updated"))
+	checkErr(t, err, "update failed")
+	if changed {
+		t.Fatalf("expected no change")
 	}
 
-	err = jsm.SamplePercent(20)(cfg)
-	checkErr(t, err, "good percent errored")
-	if cfg.SampleFrequency != "20%" {
-		t.Fatal("expected 20 pct string")
+	_, err = update.UpdateConfigurationIfChanged(jsm.AcknowledgeNone())
+	if !errors.Is(err, jsm.ErrConsumerExistsDifferentConfig) {
+		t.Fatalf("expected immutable change error got %v", err)
 	}
 }
 
-func TestStartAtSequence(t *testing.T) {
+func TestConsumer_ReplayProgress(t *testing.T) {
+	srv, nc, stream, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	stream.Purge()
+
+	for i := 0; i < 10; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	consumer, err := mgr.NewConsumer("ORDERS", jsm.DurableName("REPLAY"), jsm.ReplayAsReceived())
+	checkErr(t, err, "create failed")
+
+	progress, err := consumer.ReplayProgress(context.Background())
+	checkErr(t, err, "progress failed")
+	if progress != 0 {
+		t.Fatalf("expected 0 progress got %f", progress)
+	}
+
+	for i := 0; i < 5; i++ {
+		msg, err := consumer.NextMsg()
+		checkErr(t, err, "next failed")
+		msg.Ack()
+	}
+
+	progress, err = consumer.ReplayProgress(context.Background())
+	checkErr(t, err, "progress failed")
+	if progress != 0.5 {
+		t.Fatalf("expected 0.5 progress got %f", progress)
+	}
+
+	for i := 0; i < 5; i++ {
+		msg, err := consumer.NextMsg()
+		checkErr(t, err, "next failed")
+		msg.Ack()
+	}
+
+	progress, err = consumer.ReplayProgress(context.Background())
+	checkErr(t, err, "progress failed")
+	if progress != 1 {
+		t.Fatalf("expected 1 progress got %f", progress)
+	}
+}
+
+func TestNewConsumer_FilterCoverageCheck(t *testing.T) {
+	srv, nc, _, _ := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	mgr, err := jsm.New(nc, jsm.WithFilterCoverageCheck())
+	checkErr(t, err, "manager failed")
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.FilterStreamBySubject("ORDER.new"))
+	if err == nil || err.Error() != "consumer filter subjects ORDER.new do not match any subject in stream ORDERS" {
+		t.Fatalf("expected coverage error got %v", err)
+	}
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.FilterStreamBySubject("ORDER.new", "ORDERS.*.new"))
+	checkErr(t, err, "create failed")
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.FilterStreamBySubject("*.new"))
+	checkErr(t, err, "create failed")
+}
+
+func TestNewConsumer_FilterOverlap(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := mgr.NewConsumer("ORDERS", jsm.FilterStreamBySubject("ORDERS.new", "ORDERS.*", "ORDERS.shipped"))
+	if err == nil || err.Error() != `filter subjects "ORDERS.new" and "ORDERS.*" overlap` {
+		t.Fatalf("expected overlap error got %v", err)
+	}
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.FilterStreamBySubject("ORDERS.new", "ORDERS.new"))
+	if err == nil || err.Error() != `filter subjects "ORDERS.new" and "ORDERS.new" overlap` {
+		t.Fatalf("expected overlap error got %v", err)
+	}
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.FilterStreamBySubject("ORDERS.new", "ORDERS.shipped.>"))
+	checkErr(t, err, "create failed")
+}
+
+func TestNewConsumer_DefaultMetadata(t *testing.T) {
+	srv, nc, _, _ := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	mgr, err := jsm.New(nc, jsm.WithDefaultConsumerMetadata(map[string]string{"env": "prod", "owner": "platform"}))
+	checkErr(t, err, "manager failed")
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.ConsumerMetadata(map[string]string{"owner": "orders"}))
+	checkErr(t, err, "create failed")
+	if c.Metadata()["env"] != "prod" || c.Metadata()["owner"] != "orders" {
+		t.Fatalf("invalid metadata: %v", c.Metadata())
+	}
+
+	c, err = mgr.LoadOrNewConsumer("ORDERS", "C2")
+	checkErr(t, err, "create failed")
+	if c.Metadata()["env"] != "prod" || c.Metadata()["owner"] != "platform" {
+		t.Fatalf("invalid metadata: %v", c.Metadata())
+	}
+}
+
+func TestNewConsumer_MetadataValidation(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.ConsumerMetadata(map[string]string{"big": strings.Repeat("x", 128*1024)}))
+	if err == nil || !strings.Contains(err.Error(), "metadata size of 131075 bytes exceeds the limit of 131072 bytes") {
+		t.Fatalf("expected size error got %v", err)
+	}
+
+	lmgr, err := jsm.New(nc, jsm.WithConsumerMetadataLimit(10))
+	checkErr(t, err, "manager failed")
+	_, err = lmgr.NewConsumer("ORDERS", jsm.ConsumerMetadata(map[string]string{"team": "orders"}), jsm.AddConsumerMetadata(map[string]string{"env": "prod"}))
+	if err == nil || !strings.Contains(err.Error(), "metadata size of 17 bytes exceeds the limit of 10 bytes") {
+		t.Fatalf("expected limit error got %v", err)
+	}
+
+	_, err = jsm.New(nc, jsm.WithConsumerMetadataLimit(129*1024))
+	if err == nil {
+		t.Fatalf("expected limits above the server limit to fail")
+	}
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.AddConsumerMetadata(map[string]string{"_nats.level": "1"}))
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Fatalf("expected reserved key error got %v", err)
+	}
+
+	_, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.AddConsumerMetadata(map[string]string{"_nats.level": "1"}))
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Fatalf("expected reserved key error got %v", err)
+	}
+
+	rmgr, err := jsm.New(nc, jsm.WithReservedConsumerMetadata())
+	checkErr(t, err, "manager failed")
+	c, err := rmgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.ConsumerMetadata(map[string]string{"team": "orders"}), jsm.AddConsumerMetadata(map[string]string{"_nats.level": "1"}))
+	checkErr(t, err, "create failed")
+	if !cmp.Equal(c.Metadata(), map[string]string{"team": "orders", "_nats.level": "1"}) {
+		t.Fatalf("invalid metadata: %v", c.Metadata())
+	}
+
+	err = c.UpdateConfiguration(jsm.ConsumerDescription("updated"))
+	checkErr(t, err, "update failed")
+
+	// configurations loaded from the server carry keys set by the server, these are not copied
+	live := c.Configuration()
+	live.Durable = "C2"
+	live.Name = "C2"
+	live.Metadata = map[string]string{"team": "orders", "_nats.req.level": "0"}
+
+	c2, err := mgr.NewConsumerFromDefault("ORDERS", jsm.DefaultConsumer, jsm.FromConfig(live))
+	checkErr(t, err, "create from config failed")
+	if c2.Metadata()["team"] != "orders" {
+		t.Fatalf("invalid metadata: %v", c2.Metadata())
+	}
+	if _, ok := c2.Metadata()["_nats.req.level"]; ok {
+		t.Fatalf("expected server metadata to not be copied: %v", c2.Metadata())
+	}
+}
+
+func TestConsumer_PushSubscribeOptions(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	pull, err := mgr.NewConsumer("ORDERS", jsm.DurableName("PULL"))
+	checkErr(t, err, "create failed")
+	_, _, err = pull.PushSubscribeOptions()
+	if err == nil {
+		t.Fatalf("expected pull consumer error")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("PUSH"), jsm.DeliverySubject("out"), jsm.DeliverGroup("workers"), jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "create failed")
+
+	subject, opts, err := c.PushSubscribeOptions()
+	checkErr(t, err, "options failed")
+	if subject != "out" {
+		t.Fatalf("invalid subject %q", subject)
+	}
+
+	js, err := nc.JetStream()
+	checkErr(t, err, "jetstream failed")
+
+	msgs := make(chan *nats.Msg, 1)
+	sub, err := js.QueueSubscribe(c.FilterSubject(), c.DeliverGroup(), func(msg *nats.Msg) { msgs <- msg }, opts...)
+	checkErr(t, err, "subscribe failed")
+	defer sub.Unsubscribe()
+
+	select {
+	case msg := <-msgs:
+		if string(msg.Data) != "order 1" {
+			t.Fatalf("invalid message %q", msg.Data)
+		}
+		checkErr(t, msg.Ack(), "ack failed")
+	case <-time.After(time.Second):
+		t.Fatalf("no message received")
+	}
+}
+
+func TestManager_SwapConsumers(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := mgr.NewConsumer("ORDERS", jsm.DurableName("BLUE"), jsm.ConsumerDescription("blue"))
+	checkErr(t, err, "create failed")
+	green, err := mgr.NewConsumer("ORDERS", jsm.DurableName("GREEN"), jsm.ConsumerDescription("green"))
+	checkErr(t, err, "create failed")
+
+	msg, err := green.NextMsg()
+	checkErr(t, err, "next failed")
+	err = msg.Respond(api.AckAck)
+	checkErr(t, err, "ack failed")
+	nc.Flush()
+
+	err = mgr.SwapConsumers("ORDERS", "BLUE", "GREEN")
+	checkErr(t, err, "swap failed")
+
+	blue, err := mgr.LoadConsumer("ORDERS", "BLUE")
+	checkErr(t, err, "load failed")
+	if blue.Description() != "green" {
+		t.Fatalf("expected green description got %q", blue.Description())
+	}
+	if blue.DeliverPolicy() != api.DeliverByStartSequence || blue.StartSequence() != 2 {
+		t.Fatalf("expected start at sequence 2 got %s %d", blue.DeliverPolicy(), blue.StartSequence())
+	}
+
+	green, err = mgr.LoadConsumer("ORDERS", "GREEN")
+	checkErr(t, err, "load failed")
+	if green.Description() != "blue" {
+		t.Fatalf("expected blue description got %q", green.Description())
+	}
+	if green.DeliverPolicy() != api.DeliverAll {
+		t.Fatalf("expected deliver all got %s", green.DeliverPolicy())
+	}
+
+	err = mgr.SwapConsumers("ORDERS", "BLUE", "BLUE")
+	if err == nil {
+		t.Fatalf("expected self swap to fail")
+	}
+}
+
+func TestManager_SyncConsumerReplicasToStream(t *testing.T) {
+	withJSCluster(t, func(t *testing.T, _ []*server.Server, nc *nats.Conn, mgr *jsm.Manager) {
+		stream, err := mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.Replicas(1), jsm.MemoryStorage())
+		checkErr(t, err, "create failed")
+
+		follow, err := mgr.NewConsumer("ORDERS", jsm.DurableName("FOLLOW"), jsm.ConsumerOverrideReplicas(1), jsm.ConsumerMetadata(map[string]string{"team": "orders"}), jsm.FollowStreamReplicas())
+		checkErr(t, err, "create failed")
+		if !follow.FollowsStreamReplicas() || follow.Metadata()["team"] != "orders" {
+			t.Fatalf("invalid metadata: %v", follow.Metadata())
+		}
+
+		fixed, err := mgr.NewConsumer("ORDERS", jsm.DurableName("FIXED"), jsm.ConsumerOverrideReplicas(1))
+		checkErr(t, err, "create failed")
+		if fixed.FollowsStreamReplicas() {
+			t.Fatalf("expected fixed to not follow stream replicas")
+		}
+
+		checkErr(t, stream.UpdateConfiguration(stream.Configuration(), jsm.Replicas(3)), "update failed")
+
+		updated, errs := mgr.SyncConsumerReplicasToStream("ORDERS")
+		if len(errs) != 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		if len(updated) != 1 || updated[0] != "FOLLOW" {
+			t.Fatalf("expected FOLLOW to be updated got %v", updated)
+		}
+
+		checkErr(t, follow.Reset(), "reset failed")
+		checkErr(t, fixed.Reset(), "reset failed")
+		if follow.Replicas() != 3 || fixed.Replicas() != 1 {
+			t.Fatalf("expected 3 and 1 replicas got %d and %d", follow.Replicas(), fixed.Replicas())
+		}
+	})
+}
+
+func TestManager_NewConsumerOnTag(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, _, err := mgr.NewConsumerOnTag("ORDERS", "")
+	if err == nil {
+		t.Fatalf("expected an error without a tag")
+	}
+
+	_, _, err = mgr.NewConsumerOnTag("ORDERS", "ssd", jsm.DurableName("C1"))
+	if err == nil || err.Error() != `consumer placement is not supported, consumers are placed with their stream and stream ORDERS is not placed using tag "ssd"` {
+		t.Fatalf("expected placement error got %v", err)
+	}
+
+	known, err := mgr.IsKnownConsumer("ORDERS", "C1")
+	checkErr(t, err, "known failed")
+	if known {
+		t.Fatalf("expected the consumer to not be created")
+	}
+
+	// the test server is not clustered so a tagged stream, its consumer and the system account are faked
+	cluster := &api.ClusterInfo{Name: "C", Leader: "n1"}
+
+	_, err = nc.Subscribe("FAKE.STREAM.INFO.TAGGED", func(msg *nats.Msg) {
+		resp, _ := json.Marshal(api.JSApiStreamInfoResponse{JSApiResponse: api.JSApiResponse{Type: "io.nats.jetstream.api.v1.stream_info_response"}, StreamInfo: &api.StreamInfo{
+			Config:  api.StreamConfig{Name: "TAGGED", Subjects: []string{"tagged"}, Retention: api.LimitsPolicy, Storage: api.FileStorage, Replicas: 1, Placement: &api.Placement{Tags: []string{"ssd"}}},
+			Cluster: cluster,
+		}})
+		msg.Respond(resp)
+	})
+	checkErr(t, err, "subscribe failed")
+
+	_, err = nc.Subscribe("FAKE.CONSUMER.CREATE.TAGGED.C1", func(msg *nats.Msg) {
+		var req api.JSApiConsumerCreateRequest
+		json.Unmarshal(msg.Data, &req)
+
+		resp, _ := json.Marshal(api.JSApiConsumerCreateResponse{JSApiResponse: api.JSApiResponse{Type: "io.nats.jetstream.api.v1.consumer_create_response"}, ConsumerInfo: &api.ConsumerInfo{Stream: "TAGGED", Name: "C1", Config: req.Config, Cluster: cluster}})
+		msg.Respond(resp)
+	})
+	checkErr(t, err, "subscribe failed")
+
+	var tags atomic.Value
+	tags.Store(`["ssd"]`)
+	varz, err := nc.Subscribe("$SYS.REQ.SERVER.PING.VARZ", func(msg *nats.Msg) {
+		msg.Respond([]byte(fmt.Sprintf(`{"server":{"name":"n1","tags":%s},"data":{}}`, tags.Load())))
+	})
+	checkErr(t, err, "subscribe failed")
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+
+	c, verified, err := fake.NewConsumerOnTag("TAGGED", "ssd", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+	if !verified || c.Name() != "C1" {
+		t.Fatalf("expected a verified consumer C1 got %s verified %v", c.Name(), verified)
+	}
+
+	tags.Store(`["hdd"]`)
+	_, verified, err = fake.NewConsumerOnTag("TAGGED", "ssd", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+	if verified {
+		t.Fatalf("expected a leader without the tag to not be verified")
+	}
+
+	checkErr(t, varz.Unsubscribe(), "unsubscribe failed")
+	c, verified, err = fake.NewConsumerOnTag("TAGGED", "ssd", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+	if verified || c == nil {
+		t.Fatalf("expected an unverified consumer without system account access")
+	}
+}
+
+func TestManager_UpdateConsumersWhere(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for _, name := range []string{"A1", "A2", "B1"} {
+		_, err := mgr.NewConsumer("ORDERS", jsm.DurableName(name), jsm.MaxAckPending(10))
+		checkErr(t, err, "create failed")
+	}
+
+	eph, err := mgr.NewConsumer("ORDERS", jsm.ConsumerName("AEPH"), jsm.InactiveThreshold(time.Minute))
+	checkErr(t, err, "create failed")
+
+	updated, errs := mgr.UpdateConsumersWhere("ORDERS", func(nfo api.ConsumerInfo) bool {
+		return strings.HasPrefix(nfo.Name, "A")
+	}, jsm.MaxAckPending(100))
+
+	if !cmp.Equal(updated, []string{"A1", "A2"}) {
+		t.Fatalf("expected A1 and A2 to be updated got %v", updated)
+	}
+	if len(errs) != 1 || errs[eph.Name()] == nil || errs[eph.Name()].Error() != "consumer AEPH is not durable and can not be updated" {
+		t.Fatalf("expected a not durable error for the ephemeral got %v", errs)
+	}
+
+	for name, expected := range map[string]int{"A1": 100, "A2": 100, "B1": 10} {
+		c, err := mgr.LoadConsumer("ORDERS", name)
+		checkErr(t, err, "load failed")
+		if c.MaxAckPending() != expected {
+			t.Fatalf("expected %s to have max ack pending %d got %d", name, expected, c.MaxAckPending())
+		}
+	}
+}
+
+func TestConsumer_WaitForAssignment(t *testing.T) {
+	withJSCluster(t, func(t *testing.T, _ []*server.Server, nc *nats.Conn, mgr *jsm.Manager) {
+		_, err := mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.Replicas(3), jsm.MemoryStorage())
+		checkErr(t, err, "create failed")
+
+		c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+		checkErr(t, err, "create failed")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err = c.WaitForAssignment(ctx)
+		checkErr(t, err, "wait failed")
+
+		checkErr(t, c.Delete(), "delete failed")
+
+		err = c.WaitForAssignment(ctx)
+		if !jsm.IsNatsError(err, 10014) {
+			t.Fatalf("expected not found error got %v", err)
+		}
+	})
+}
+
+func TestConsumer_StateFromLeader(t *testing.T) {
+	withJSCluster(t, func(t *testing.T, _ []*server.Server, nc *nats.Conn, mgr *jsm.Manager) {
+		_, err := mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.Replicas(3), jsm.MemoryStorage())
+		checkErr(t, err, "create failed")
+
+		c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+		checkErr(t, err, "create failed")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		nfo, err := c.StateFromLeader(ctx)
+		checkErr(t, err, "state failed")
+		if nfo.Cluster == nil || nfo.Cluster.Leader == "" {
+			t.Fatalf("expected a leader: %+v", nfo.Cluster)
+		}
+
+		_, err = nc.Subscribe("FAKE.CONSUMER.INFO.ORDERS.C1", func(msg *nats.Msg) {
+			msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_info_response","stream_name":"ORDERS","name":"C1","config":{"durable_name":"C1","ack_policy":"explicit","deliver_policy":"all","replay_policy":"instant"},"cluster":{"name":"TEST"}}`))
+		})
+		checkErr(t, err, "subscribe failed")
+
+		fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"), jsm.WithTimeout(500*time.Millisecond))
+		checkErr(t, err, "manager failed")
+
+		fc, err := fake.LoadConsumer("ORDERS", "C1")
+		checkErr(t, err, "load failed")
+
+		_, err = fc.StateFromLeader(context.Background())
+		if !errors.Is(err, jsm.ErrNoLeader) {
+			t.Fatalf("expected no leader error got %v", err)
+		}
+	})
+}
+
+func TestConsumer_StateFromLeaderAttemptTimeout(t *testing.T) {
+	srv, nc, _, _ := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	var drop atomic.Bool
+	_, err := nc.Subscribe("FAKE.CONSUMER.INFO.ORDERS.C1", func(msg *nats.Msg) {
+		// the first attempt is never answered and has to time out for the retry to happen
+		if drop.CompareAndSwap(true, false) {
+			return
+		}
+		msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_info_response","stream_name":"ORDERS","name":"C1","config":{"durable_name":"C1","ack_policy":"explicit","deliver_policy":"all","replay_policy":"instant"},"cluster":{"name":"TEST","leader":"s1"}}`))
+	})
+	checkErr(t, err, "subscribe failed")
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"), jsm.WithTimeout(200*time.Millisecond))
+	checkErr(t, err, "manager failed")
+
+	c, err := fake.LoadConsumer("ORDERS", "C1")
+	checkErr(t, err, "load failed")
+
+	drop.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	nfo, err := c.StateFromLeader(ctx)
+	checkErr(t, err, "state failed")
+	if nfo.Cluster.Leader != "s1" {
+		t.Fatalf("expected s1 to be leader got %q", nfo.Cluster.Leader)
+	}
+	if drop.Load() {
+		t.Fatalf("expected the first attempt to be dropped")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("expected the dropped attempt to time out after the manager timeout, took %v", time.Since(start))
+	}
+}
+
+func TestConsumer_Info(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("INFO"))
+	checkErr(t, err, "create failed")
+
+	cached, err := c.LatestState()
+	checkErr(t, err, "state failed")
+	if cached.NumPending != 1 {
+		t.Fatalf("expected 1 pending got %d", cached.NumPending)
+	}
+
+	_, err = nc.Request("ORDERS.new", []byte("order 2"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	nfo, err := c.Info()
+	checkErr(t, err, "info failed")
+	if nfo.NumPending != 2 {
+		t.Fatalf("expected 2 pending got %d", nfo.NumPending)
+	}
+
+	latest, err := c.LatestState()
+	checkErr(t, err, "state failed")
+	if latest.NumPending != 1 {
+		t.Fatalf("expected cached state to be unchanged got %d pending", latest.NumPending)
+	}
+
+	_, err = c.State()
+	checkErr(t, err, "state failed")
+
+	latest, err = c.LatestState()
+	checkErr(t, err, "state failed")
+	if latest.NumPending != 2 {
+		t.Fatalf("expected cached state to be updated got %d pending", latest.NumPending)
+	}
+}
+
+func TestConsumer_StateRetry(t *testing.T) {
+	srv, nc, _ := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	var attempts int32
+	var failures int32
+	var notFound int32
+
+	_, err := nc.Subscribe("FAKE.CONSUMER.INFO.ORDERS.C1", func(msg *nats.Msg) {
+		switch {
+		case atomic.LoadInt32(&notFound) == 1:
+			atomic.AddInt32(&attempts, 1)
+			msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_info_response","error":{"code":404,"err_code":10014,"description":"consumer not found"}}`))
+		case atomic.AddInt32(&attempts, 1) <= atomic.LoadInt32(&failures):
+			msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_info_response","error":{"code":503,"err_code":10008,"description":"JetStream system temporarily unavailable"}}`))
+		default:
+			msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_info_response","stream_name":"ORDERS","name":"C1","config":{"durable_name":"C1","ack_policy":"explicit","deliver_policy":"all","replay_policy":"instant"},"num_pending":10}`))
+		}
+	})
+	checkErr(t, err, "subscribe failed")
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"), jsm.WithTimeout(500*time.Millisecond))
+	checkErr(t, err, "manager failed")
+
+	c, err := fake.LoadConsumer("ORDERS", "C1")
+	checkErr(t, err, "load failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	atomic.StoreInt32(&attempts, 0)
+	atomic.StoreInt32(&failures, 2)
+	nfo, err := c.StateRetry(ctx, 3)
+	checkErr(t, err, "state failed")
+	if nfo.NumPending != 10 || atomic.LoadInt32(&attempts) != 3 {
+		t.Fatalf("expected state after 3 attempts got %d attempts: %+v", atomic.LoadInt32(&attempts), nfo)
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	_, err = c.StateRetry(ctx, 2)
+	if !jsm.IsNatsError(err, 10008) || atomic.LoadInt32(&attempts) != 2 {
+		t.Fatalf("expected unavailable error after 2 attempts got %v after %d", err, atomic.LoadInt32(&attempts))
+	}
+
+	atomic.StoreInt32(&attempts, 0)
+	atomic.StoreInt32(&notFound, 1)
+	_, err = c.StateRetry(ctx, 5)
+	if !jsm.IsNatsError(err, 10014) || atomic.LoadInt32(&attempts) != 1 {
+		t.Fatalf("expected not found error after 1 attempt got %v after %d", err, atomic.LoadInt32(&attempts))
+	}
+
+	_, err = c.StateRetry(ctx, 0)
+	if err == nil {
+		t.Fatalf("expected attempts error")
+	}
+}
+
+func TestConsumer_EffectiveSubjects(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err := mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*", "SHIP.>"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, tc := range []struct {
+		filters  []string
+		expected []string
+	}{
+		{nil, []string{"ORDERS.*", "SHIP.>"}},
+		{[]string{"ORDERS.new", "SHIP.*.eu"}, []string{"ORDERS.new", "SHIP.*.eu"}},
+		{[]string{"*.new"}, []string{"ORDERS.new", "SHIP.new"}},
+		{[]string{">"}, []string{"ORDERS.*", "SHIP.>"}},
+		{[]string{"*.eu.>"}, []string{"SHIP.eu.>"}},
+	} {
+		var opts []jsm.ConsumerOption
+		if len(tc.filters) > 0 {
+			opts = append(opts, jsm.FilterStreamBySubject(tc.filters...))
+		}
+
+		c, err := mgr.NewConsumer("ORDERS", opts...)
+		checkErr(t, err, "create failed")
+
+		subjects, err := c.EffectiveSubjects(ctx)
+		checkErr(t, err, "effective subjects failed")
+		if !cmp.Equal(subjects, tc.expected) {
+			t.Fatalf("invalid subjects for %v: %s", tc.filters, cmp.Diff(tc.expected, subjects))
+		}
+	}
+
+	_, err = mgr.NewStream("MIRROR", jsm.Mirror(&api.StreamSource{Name: "ORDERS"}), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	c, err := mgr.NewConsumer("MIRROR", jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "create failed")
+
+	subjects, err := c.EffectiveSubjects(ctx)
+	checkErr(t, err, "effective subjects failed")
+	if !cmp.Equal(subjects, []string{"ORDERS.new"}) {
+		t.Fatalf("invalid mirror subjects: %v", subjects)
+	}
+}
+
+func TestConsumer_StepDownToPreferred(t *testing.T) {
+	withJSCluster(t, func(t *testing.T, _ []*server.Server, nc *nats.Conn, mgr *jsm.Manager) {
+		_, err := mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.Replicas(3), jsm.MemoryStorage())
+		checkErr(t, err, "create failed")
+
+		c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+		checkErr(t, err, "create failed")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		nfo, err := c.StateFromLeader(ctx)
+		checkErr(t, err, "state failed")
+
+		var preferred string
+		for _, peer := range nfo.Cluster.Replicas {
+			preferred = peer.Name
+		}
+
+		err = c.StepDownToPreferred(ctx, "unknown")
+		if err == nil {
+			t.Fatalf("expected unknown server error")
+		}
+
+		err = c.StepDownToPreferred(ctx, preferred)
+		checkErr(t, err, "step down failed")
+
+		nfo, err = c.StateFromLeader(ctx)
+		checkErr(t, err, "state failed")
+		if nfo.Cluster.Leader != preferred {
+			t.Fatalf("expected %s to be leader got %s", preferred, nfo.Cluster.Leader)
+		}
+	})
+}
+
+func testConsumerConfig() *api.ConsumerConfig {
+	return &api.ConsumerConfig{
+		AckWait:       0,
+		AckPolicy:     api.AckExplicit,
+		MaxDeliver:    -1,
+		ReplayPolicy:  api.ReplayInstant,
+		OptStartSeq:   0,
+		OptStartTime:  nil,
+		DeliverPolicy: api.DeliverAll,
+	}
+}
+func TestAckWait(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.AckWait(time.Hour)(cfg)
+	if cfg.AckWait != time.Hour {
+		t.Fatalf("expected 1 hour got %v", cfg.AckWait)
+	}
+}
+
+func TestAcknowledgeAll(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.AcknowledgeAll()(cfg)
+	if cfg.AckPolicy != api.AckAll {
+		t.Fatalf("expected AckAll got %s", cfg.AckPolicy)
+	}
+}
+
+func TestAcknowledgeExplicit(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.AcknowledgeExplicit()(cfg)
+	if cfg.AckPolicy != api.AckExplicit {
+		t.Fatalf("expected AckExplicit got %s", cfg.AckPolicy)
+	}
+}
+
+func TestAcknowledgeNone(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.AcknowledgeNone()(cfg)
+	if cfg.AckPolicy != api.AckNone {
+		t.Fatalf("expected AckNone got %s", cfg.AckPolicy)
+	}
+}
+
+func TestDeliverAllAvailable(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.DeliverAllAvailable()(cfg)
+	if cfg.DeliverPolicy != api.DeliverAll {
+		t.Fatal("expected DeliverAll")
+	}
+}
+
+func TestDeliverySubject(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.DeliverySubject("out")(cfg)
+	if cfg.DeliverSubject != "out" {
+		t.Fatalf("expected 'out' got %q", cfg.DeliverSubject)
+	}
+}
+
+func TestDurableName(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.DurableName("test")(cfg)
+	if cfg.Durable != "test" {
+		t.Fatalf("expected 'test' got %q", cfg.Durable)
+	}
+}
+
+func TestNamedEphemeral(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	cfg := testConsumerConfig()
+	cfg.Durable = "DURABLE"
+	checkErr(t, jsm.NamedEphemeral("test")(cfg), "option failed")
+	if cfg.Name != "test" || cfg.Durable != "" {
+		t.Fatalf("expected a named ephemeral config: %+v", cfg)
+	}
+
+	named, err := mgr.NewConsumer("ORDERS", jsm.NamedEphemeral("NAMED"), jsm.InactiveThreshold(time.Minute))
+	checkErr(t, err, "create failed")
+	if named.Name() != "NAMED" || !named.IsEphemeral() || !named.IsNamedEphemeral() {
+		t.Fatalf("expected a named ephemeral consumer: %+v", named.Configuration())
+	}
+
+	anon, err := mgr.NewConsumer("ORDERS")
+	checkErr(t, err, "create failed")
+	if !anon.IsEphemeral() || anon.IsNamedEphemeral() {
+		t.Fatalf("expected an anonymous ephemeral consumer: %+v", anon.Configuration())
+	}
+
+	durable, err := mgr.NewConsumer("ORDERS", jsm.DurableName("DURABLE"))
+	checkErr(t, err, "create failed")
+	if durable.IsEphemeral() || durable.IsNamedEphemeral() {
+		t.Fatalf("expected a durable consumer: %+v", durable.Configuration())
+	}
+
+	// names that look generated are still named when chosen by the caller
+	worker, err := mgr.NewConsumer("ORDERS", jsm.NamedEphemeral("WORKER01"), jsm.InactiveThreshold(time.Minute))
+	checkErr(t, err, "create failed")
+	if !worker.IsNamedEphemeral() {
+		t.Fatalf("expected WORKER01 to be a named ephemeral consumer")
+	}
+	checkErr(t, worker.UpdateConfiguration(jsm.ConsumerDescription("worker")), "update failed")
+
+	if worker.Metadata()[jsm.NamedEphemeralMetadataKey] != "true" {
+		t.Fatalf("expected the named ephemeral marker: %v", worker.Metadata())
+	}
+
+	// the marker is stored with the consumer so every handle agrees, however it was obtained
+	loaded, err := mgr.LoadConsumer("ORDERS", "WORKER01")
+	checkErr(t, err, "load failed")
+	if !loaded.IsNamedEphemeral() {
+		t.Fatalf("expected a loaded consumer to be named")
+	}
+
+	loaded, err = mgr.LoadConsumer("ORDERS", anon.Name())
+	checkErr(t, err, "load failed")
+	if loaded.IsNamedEphemeral() {
+		t.Fatalf("expected a loaded anonymous consumer to not be named")
+	}
+
+	consumers, _, err := mgr.Consumers("ORDERS")
+	checkErr(t, err, "list failed")
+	for _, c := range consumers {
+		if c.IsNamedEphemeral() != (c.Name() == "NAMED" || c.Name() == "WORKER01") {
+			t.Fatalf("invalid named ephemeral state for listed consumer %s", c.Name())
+		}
+	}
+
+	err = anon.UpdateConfiguration(jsm.ConsumerDescription("anon"))
+	if err == nil {
+		t.Fatalf("expected anonymous ephemeral update to fail")
+	}
+
+	err = jsm.NamedEphemeral("a.b")(cfg)
+	if err == nil {
+		t.Fatalf("expected invalid name error")
+	}
+}
+
+func TestFilterStreamBySubject(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.FilterStreamBySubject("test")(cfg)
+	if cfg.FilterSubject != "test" {
+		t.Fatalf("expected 'test' got %q", cfg.FilterSubject)
+	}
+
+	if len(cfg.FilterSubjects) > 0 {
+		t.Fatalf("expected no filter subjects got %v", cfg.FilterSubjects)
+	}
+
+	cfg = testConsumerConfig()
+	jsm.FilterStreamBySubject("test", "1", "2", "3")(cfg)
+	if cfg.FilterSubject != "" {
+		t.Fatalf("expected '' got %q", cfg.FilterSubject)
+	}
+	if !cmp.Equal([]string{"test", "1", "2", "3"}, cfg.FilterSubjects) {
+		t.Fatalf("expected [test, 1, 2, 3] got %v", cfg.FilterSubjects)
+	}
+}
+
+func TestMaxDeliveryAttempts(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.MaxDeliveryAttempts(10)(cfg)
+	if cfg.MaxDeliver != 10 {
+		t.Fatalf("expected 10 got %q", cfg.MaxDeliver)
+	}
+
+	err := jsm.MaxDeliveryAttempts(0)
+	if err == nil {
+		t.Fatalf("expected 0 deliveries to fail")
+	}
+
+	if jsm.MaxDeliveryAttempts(-2)(cfg) == nil {
+		t.Fatalf("expected -2 deliveries to fail")
+	}
+
+	checkErr(t, jsm.UnlimitedDeliveryAttempts()(cfg), "unlimited failed")
+	if cfg.MaxDeliver != -1 {
+		t.Fatalf("expected -1 got %d", cfg.MaxDeliver)
+	}
+}
+
+func TestConsumer_IsUnlimitedDelivery(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("UNLIMITED"), jsm.UnlimitedDeliveryAttempts())
+	checkErr(t, err, "create failed")
+	if !c.IsUnlimitedDelivery() {
+		t.Fatalf("expected unlimited delivery")
+	}
+
+	c, err = mgr.NewConsumer("ORDERS", jsm.DurableName("LIMITED"), jsm.MaxDeliveryAttempts(5))
+	checkErr(t, err, "create failed")
+	if c.IsUnlimitedDelivery() {
+		t.Fatalf("expected limited delivery")
+	}
+}
+
+func TestFilterFromStreamSubjects(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := mgr.NewStream("MULTI", jsm.Subjects("a.>", "b.*", "c"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	cfg := testConsumerConfig()
+	err = jsm.FilterFromStreamSubjects(mgr, "ORDERS")(cfg)
+	checkErr(t, err, "option failed")
+	if cfg.FilterSubject != "" || len(cfg.FilterSubjects) != 0 {
+		t.Fatalf("expected no filters got %q %v", cfg.FilterSubject, cfg.FilterSubjects)
+	}
+
+	cfg = testConsumerConfig()
+	err = jsm.FilterFromStreamSubjects(mgr, "MULTI")(cfg)
+	checkErr(t, err, "option failed")
+	if !cmp.Equal(cfg.FilterSubjects, []string{"a.>", "b.*", "c"}) {
+		t.Fatalf("expected stream subjects got %v", cfg.FilterSubjects)
+	}
+
+	err = jsm.FilterFromStreamSubjects(mgr, "UNKNOWN")(cfg)
+	if err == nil {
+		t.Fatalf("expected unknown stream to fail")
+	}
+}
+
+func TestReplayAsReceived(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.ReplayAsReceived()(cfg)
+	if cfg.ReplayPolicy != api.ReplayOriginal {
+		t.Fatalf("expected ReplayOriginal got %s", cfg.ReplayPolicy)
+	}
+}
+
+func TestReplayInstantly(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.ReplayInstantly()(cfg)
+	if cfg.ReplayPolicy != api.ReplayInstant {
+		t.Fatalf("expected ReplayInstant got %s", cfg.ReplayPolicy)
+	}
+}
+
+func TestSamplePercent(t *testing.T) {
+	cfg := testConsumerConfig()
+	err := jsm.SamplePercent(200)(cfg)
+	if err == nil {
+		t.Fatal("impossible percent didnt error")
+	}
+
+	err = jsm.SamplePercent(-1)(cfg)
+	if err == nil {
+		t.Fatal("impossible percent didnt error")
+	}
+
+	err = jsm.SamplePercent(0)(cfg)
+	checkErr(t, err, "good percent errored")
+	if cfg.SampleFrequency != "" {
+		t.Fatal("expected empty string")
+	}
+
+	err = jsm.SamplePercent(20)(cfg)
+	checkErr(t, err, "good percent errored")
+	if cfg.SampleFrequency != "20%" {
+		t.Fatal("expected 20 pct string")
+	}
+}
+
+func TestSampleFraction(t *testing.T) {
+	cfg := testConsumerConfig()
+	err := jsm.SampleFraction(100.1)(cfg)
+	if err == nil {
+		t.Fatal("impossible fraction didnt error")
+	}
+
+	err = jsm.SampleFraction(-0.1)(cfg)
+	if err == nil {
+		t.Fatal("impossible fraction didnt error")
+	}
+
+	// the server rejects sample frequencies that are not whole percentages
+	err = jsm.SampleFraction(0.1)(cfg)
+	if err == nil || err.Error() != "sample fraction must be a whole percentage, the server does not support sampling 0.1%" {
+		t.Fatalf("expected fractional percentage error got %v", err)
+	}
+
+	err = jsm.SampleFraction(20)(cfg)
+	checkErr(t, err, "good fraction errored")
+	if cfg.SampleFrequency != "20%" {
+		t.Fatalf("expected 20%% got %q", cfg.SampleFrequency)
+	}
+
+	err = jsm.SampleFraction(0)(cfg)
+	checkErr(t, err, "good fraction errored")
+	if cfg.SampleFrequency != "" {
+		t.Fatal("expected empty string")
+	}
+
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("SAMPLED"), jsm.SampleFraction(20))
+	checkErr(t, err, "create failed")
+	if c.SampleFrequency() != "20%" || c.SamplePercentage() != 20 {
+		t.Fatalf("expected 20%% sampling got %q", c.SampleFrequency())
+	}
+}
+
+func TestConsumer_SamplePercentage(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.SamplePercent(20))
+	checkErr(t, err, "create failed")
+	if c.SamplePercentage() != 20 {
+		t.Fatalf("expected 20 got %f", c.SamplePercentage())
+	}
+
+	c, err = mgr.NewConsumer("ORDERS")
+	checkErr(t, err, "create failed")
+	if c.SamplePercentage() != 0 {
+		t.Fatalf("expected 0 got %f", c.SamplePercentage())
+	}
+}
+
+func TestStartAtSequence(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.StartAtSequence(1024)(cfg)
+	if cfg.DeliverPolicy != api.DeliverByStartSequence || cfg.OptStartSeq != 1024 {
+		t.Fatal("expected 1024")
+	}
+}
+
+func TestStartAtTime(t *testing.T) {
+	cfg := testConsumerConfig()
+	s := time.Now().Add(-1 * time.Hour)
+	jsm.StartAtTime(s)(cfg)
+	if cfg.DeliverPolicy != api.DeliverByStartTime || cfg.OptStartTime.Unix() != s.Unix() {
+		t.Fatal("expected 1 hour delta")
+	}
+}
+
+func TestStartAtTimeDelta(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.StartAtTimeDelta(time.Hour)(cfg)
+	if cfg.DeliverPolicy != api.DeliverByStartTime || cfg.OptStartTime.Unix() < time.Now().Add(-1*time.Hour-time.Second).Unix() || cfg.OptStartTime.Unix() > time.Now().Add(-1*time.Hour+time.Second).Unix() {
+		t.Fatal("expected ~ 1 hour delta")
+	}
+}
+
+func TestDeliverLastPerSubject(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.DeliverLastPerSubject()(cfg)
+	if cfg.DeliverPolicy != api.DeliverLastPerSubject {
+		t.Fatal("expected DeliverLastPerSubject")
+	}
+}
+
+func TestStartWithLastReceived(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.StartWithLastReceived()(cfg)
+	if cfg.DeliverPolicy != api.DeliverLast {
+		t.Fatal("expected DeliverLast")
+	}
+}
+
+func TestStartWithNextReceived(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.StartWithNextReceived()(cfg)
+	if cfg.DeliverPolicy != api.DeliverNew {
+		t.Fatal("expected DeliverNew")
+	}
+}
+
+func TestRateLimitBitPerSecond(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.RateLimitBitsPerSecond(10)(cfg)
+	if cfg.RateLimit != 10 {
+		t.Fatal("expected RateLimit==10")
+	}
+}
+
+func TestMaxAckOutstanding(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.MaxAckPending(10)(cfg)
+	if cfg.MaxAckPending != 10 {
+		t.Fatal("expected MaxAckPending==10")
+	}
+}
+
+func TestIdleHeartbeat(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.IdleHeartbeat(time.Second)(cfg)
+	if cfg.Heartbeat != time.Second {
+		t.Fatalf("expected Heartbeat==1s")
+	}
+}
+
+func TestPushFlowControl(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.PushFlowControl()(cfg)
+	if !cfg.FlowControl {
+		t.Fatalf("expected FlowControl==true")
+	}
+}
+
+func TestDeliverGroup(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.DeliverGroup("bob")(cfg)
+	if cfg.DeliverGroup != "bob" {
+		t.Fatalf("expected DeliverGroup==bob")
+	}
+}
+
+func TestMaxWaiting(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.MaxWaiting(10)(cfg)
+	if cfg.MaxWaiting != 10 {
+		t.Fatalf("expected MaxWaiting==10")
+	}
+}
+
+func TestDeliverHeadersOnly(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.DeliverHeadersOnly()(cfg)
+	if !cfg.HeadersOnly {
+		t.Fatalf("expected headers only to be set")
+	}
+}
+
+func TestNewConsumer_HeadersOnlyValidation(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := mgr.NewConsumer("ORDERS", jsm.DeliverHeadersOnly(), jsm.PushFlowControl())
+	if err == nil || err.Error() != "headers only consumers using push delivery settings require a deliver subject" {
+		t.Fatalf("expected deliver subject error got %v", err)
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DeliverHeadersOnly(), jsm.DeliverySubject(nc.NewRespInbox()), jsm.PushFlowControl(), jsm.IdleHeartbeat(time.Second))
+	checkErr(t, err, "create failed")
+	if !c.IsHeadersOnly() {
+		t.Fatalf("expected headers only")
+	}
+
+	c, err = mgr.NewConsumer("ORDERS")
+	checkErr(t, err, "create failed")
+	if c.IsHeadersOnly() {
+		t.Fatalf("expected full messages")
+	}
+}
+
+func TestMaxRequestBatch(t *testing.T) {
+	cfg := testConsumerConfig()
+	jsm.MaxRequestBatch(10)(cfg)
+	if cfg.MaxRequestBatch != 10 {
+		t.Fatalf("expected max request batch of 10: %v", cfg.MaxRequestBatch)
+	}
+}
+
+func TestMaxRequestExpires(t *testing.T) {
+	cfg := testConsumerConfig()
+	err := jsm.MaxRequestExpires(10 * time.Microsecond)(cfg)
+	if err == nil || err.Error() != "must be larger than 1ms" {
+		t.Fatalf("expected 1ms error got: %v", err)
+	}
+
+	err = jsm.MaxRequestExpires(time.Minute)(cfg)
+	if err != nil {
+		t.Fatalf("exptected no error: %v", err)
+	}
+
+	if cfg.MaxRequestExpires != time.Minute {
+		t.Fatalf("expected max request expires of 1 minute: %v", cfg.MaxRequestExpires)
+	}
+}
+
+func TestMaxRequestBatchAtMost(t *testing.T) {
+	cfg := testConsumerConfig()
+	checkErr(t, jsm.MaxRequestBatchAtMost(10)(cfg), "option failed")
+	if cfg.MaxRequestBatch != 10 {
+		t.Fatalf("expected max request batch of 10: %v", cfg.MaxRequestBatch)
+	}
+
+	checkErr(t, jsm.MaxRequestBatchAtMost(20)(cfg), "option failed")
+	if cfg.MaxRequestBatch != 10 {
+		t.Fatalf("expected max request batch of 10: %v", cfg.MaxRequestBatch)
+	}
+
+	checkErr(t, jsm.MaxRequestBatchAtMost(5)(cfg), "option failed")
+	checkErr(t, jsm.MaxRequestBatchAtMost(0)(cfg), "option failed")
+	if cfg.MaxRequestBatch != 5 {
+		t.Fatalf("expected max request batch of 5: %v", cfg.MaxRequestBatch)
+	}
+}
+
+func TestMaxRequestExpiresAtMost(t *testing.T) {
 	cfg := testConsumerConfig()
-	jsm.StartAtSequence(1024)(cfg)
-	if cfg.DeliverPolicy != api.DeliverByStartSequence || cfg.OptStartSeq != 1024 {
-		t.Fatal("expected 1024")
+	err := jsm.MaxRequestExpiresAtMost(10 * time.Microsecond)(cfg)
+	if err == nil || err.Error() != "must be larger than 1ms" {
+		t.Fatalf("expected 1ms error got: %v", err)
+	}
+
+	checkErr(t, jsm.MaxRequestExpiresAtMost(time.Minute)(cfg), "option failed")
+	checkErr(t, jsm.MaxRequestExpiresAtMost(time.Hour)(cfg), "option failed")
+	if cfg.MaxRequestExpires != time.Minute {
+		t.Fatalf("expected max request expires of 1 minute: %v", cfg.MaxRequestExpires)
+	}
+
+	checkErr(t, jsm.MaxRequestExpiresAtMost(time.Second)(cfg), "option failed")
+	checkErr(t, jsm.MaxRequestExpiresAtMost(0)(cfg), "option failed")
+	if cfg.MaxRequestExpires != time.Second {
+		t.Fatalf("expected max request expires of 1 second: %v", cfg.MaxRequestExpires)
+	}
+}
+
+func TestPullFleetLimits(t *testing.T) {
+	cfg := testConsumerConfig()
+	cfg.MaxWaiting = 0
+	checkErr(t, jsm.PullFleetLimits(20, 50)(cfg), "option failed")
+	if cfg.MaxWaiting != 20 || cfg.MaxRequestBatch != 50 {
+		t.Fatalf("expected max waiting 20 and max request batch 50: %v %v", cfg.MaxWaiting, cfg.MaxRequestBatch)
+	}
+
+	cfg.MaxWaiting = 512
+	checkErr(t, jsm.PullFleetLimits(20, 10)(cfg), "option failed")
+	if cfg.MaxWaiting != 512 || cfg.MaxRequestBatch != 10 {
+		t.Fatalf("expected max waiting 512 and max request batch 10: %v %v", cfg.MaxWaiting, cfg.MaxRequestBatch)
+	}
+
+	if jsm.PullFleetLimits(0, 10)(cfg) == nil || jsm.PullFleetLimits(10, 0)(cfg) == nil {
+		t.Fatalf("expected non positive values to fail")
+	}
+}
+
+func TestMaxAckPendingPerReplica(t *testing.T) {
+	cfg, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.MaxAckPendingPerReplica(100), jsm.ConsumerOverrideReplicas(3))
+	checkErr(t, err, "configuration failed")
+	if cfg.MaxAckPending != 300 {
+		t.Fatalf("expected max ack pending 300 got %d", cfg.MaxAckPending)
+	}
+
+	_, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.MaxAckPendingPerReplica(100))
+	if err == nil {
+		t.Fatalf("expected an unknown replica count to fail")
+	}
+
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.MaxAckPendingPerReplica(100))
+	checkErr(t, err, "create failed")
+	if c.MaxAckPending() != 100 {
+		t.Fatalf("expected max ack pending 100 got %d", c.MaxAckPending())
+	}
+
+	// the consumer inherits the stream replicas so these are loaded when updating
+	diff, err := c.ConfigDifference(jsm.MaxAckPendingPerReplica(50))
+	checkErr(t, err, "difference failed")
+	if len(diff) != 2 || !strings.HasPrefix(diff[0], "MaxAckPending: 100 != 50") || !strings.HasPrefix(diff[1], "Metadata:") {
+		t.Fatalf("expected max ack pending difference got %v", diff)
+	}
+
+	changed, err := c.UpdateConfigurationIfChanged(jsm.MaxAckPendingPerReplica(50))
+	checkErr(t, err, "update failed")
+	if !changed || c.MaxAckPending() != 50 {
+		t.Fatalf("expected max ack pending 50 got %d changed %v", c.MaxAckPending(), changed)
+	}
+
+	nfo, err := c.State()
+	checkErr(t, err, "state failed")
+	if nfo.Config.MaxAckPending != 50 {
+		t.Fatalf("expected server max ack pending 50 got %d", nfo.Config.MaxAckPending)
+	}
+
+	changed, err = c.UpdateConfigurationIfChanged(jsm.MaxAckPendingPerReplica(50))
+	checkErr(t, err, "update failed")
+	if changed {
+		t.Fatalf("expected no change")
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	base := api.ConsumerConfig{
+		Description:    "base",
+		AckPolicy:      api.AckExplicit,
+		AckWait:        time.Minute,
+		FilterSubjects: []string{"ORDERS.new", "ORDERS.shipped"},
+		BackOff:        []time.Duration{time.Second, 2 * time.Second},
+		OptStartTime:   &start,
+		Metadata:       map[string]string{"team": "orders"},
+	}
+
+	cfg, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.FromConfig(base), jsm.AckWait(time.Hour))
+	checkErr(t, err, "configuration failed")
+
+	if cfg.Description != "base" || cfg.AckWait != time.Hour || cfg.AckPolicy != api.AckExplicit {
+		t.Fatalf("invalid configuration: %+v", cfg)
+	}
+
+	cfg.FilterSubjects[0] = "ORDERS.changed"
+	cfg.BackOff[0] = time.Hour
+	cfg.Metadata["team"] = "changed"
+	*cfg.OptStartTime = time.Now()
+
+	if base.FilterSubjects[0] != "ORDERS.new" || base.BackOff[0] != time.Second || base.Metadata["team"] != "orders" || !base.OptStartTime.Equal(start) {
+		t.Fatalf("configuration was aliased: %+v", base)
+	}
+}
+
+func TestConsumer_RequestedConfig(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumerFromDefault("ORDERS", api.ConsumerConfig{AckPolicy: api.AckExplicit}, jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	requested, ok := c.RequestedConfig()
+	if !ok {
+		t.Fatalf("expected a requested configuration")
+	}
+	if requested.AckWait != 0 || requested.MaxDeliver != 0 {
+		t.Fatalf("expected defaults to be unset in requested config: %+v", requested)
+	}
+
+	server := c.ServerConfig()
+	if server.AckWait != 30*time.Second || server.MaxDeliver != -1 {
+		t.Fatalf("expected server defaults in server config: %+v", server)
+	}
+
+	checkErr(t, c.UpdateConfiguration(jsm.ConsumerDescription("updated")), "update failed")
+	requested, _ = c.RequestedConfig()
+	if requested.Description != "updated" {
+		t.Fatalf("expected requested config to be updated: %+v", requested)
+	}
+
+	loaded, err := mgr.LoadConsumer("ORDERS", "C1")
+	checkErr(t, err, "load failed")
+	_, ok = loaded.RequestedConfig()
+	if ok {
+		t.Fatalf("expected no requested config for loaded consumers")
+	}
+}
+
+func TestConsumer_AckSubjectFor(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err := nc.Request("ORDERS.new", []byte("1"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+	other, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C2"))
+	checkErr(t, err, "create failed")
+
+	msg, err := c.NextMsg()
+	checkErr(t, err, "next failed")
+
+	subj, err := c.AckSubjectFor(msg)
+	checkErr(t, err, "ack subject failed")
+	if subj != msg.Reply {
+		t.Fatalf("expected %q got %q", msg.Reply, subj)
+	}
+
+	_, err = other.AckSubjectFor(msg)
+	if err == nil {
+		t.Fatalf("expected an error for a message from another consumer")
+	}
+
+	_, err = c.AckSubjectFor(nats.NewMsg("x"))
+	if err == nil {
+		t.Fatalf("expected an error for a non JetStream message")
+	}
+}
+
+func TestConsumer_ResetState(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.ConsumerMetadata(map[string]string{"team": "orders"}))
+	checkErr(t, err, "create failed")
+
+	msg, err := c.NextMsg()
+	checkErr(t, err, "next failed")
+	_, err = nc.Request(msg.Reply, api.AckAck, time.Second)
+	checkErr(t, err, "ack failed")
+
+	nfo, err := c.State()
+	checkErr(t, err, "state failed")
+	if nfo.AckFloor.Stream != 1 {
+		t.Fatalf("expected ack floor 1 got %d", nfo.AckFloor.Stream)
+	}
+
+	checkErr(t, c.ResetState(), "reset failed")
+
+	nfo, err = c.State()
+	checkErr(t, err, "state failed")
+	if nfo.AckFloor.Stream != 0 || nfo.NumPending != 1 {
+		t.Fatalf("expected reset state got %+v", nfo)
+	}
+	if c.DurableName() != "C1" || c.Metadata()["team"] != "orders" {
+		t.Fatalf("expected configuration to be preserved: %+v", c.Configuration())
+	}
+}
+
+func TestConsumer_StreamRetention(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	wq, err := c.IsWorkQueueConsumer(context.Background())
+	checkErr(t, err, "retention failed")
+	if wq {
+		t.Fatalf("expected a limits stream")
+	}
+
+	_, err = mgr.NewStream("JOBS", jsm.Subjects("JOBS.*"), jsm.WorkQueueRetention(), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+	c, err = mgr.NewConsumer("JOBS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	retention, err := c.StreamRetention(context.Background())
+	checkErr(t, err, "retention failed")
+	if retention != api.WorkQueuePolicy {
+		t.Fatalf("expected work queue retention got %v", retention)
+	}
+
+	wq, err = c.IsWorkQueueConsumer(context.Background())
+	checkErr(t, err, "retention failed")
+	if !wq {
+		t.Fatalf("expected a work queue consumer")
+	}
+}
+
+func TestConsumer_UpdatableDiff(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.ConsumerDescription("old"))
+	checkErr(t, err, "create failed")
+
+	desired := c.Configuration()
+	desired.Description = "new"
+	desired.MaxAckPending = 10
+	desired.AckPolicy = api.AckAll
+	desired.DeliverPolicy = api.DeliverNew
+
+	updatable, immutable, err := c.UpdatableDiff(desired)
+	checkErr(t, err, "diff failed")
+	if !cmp.Equal(updatable, []string{"Description", "MaxAckPending"}) {
+		t.Fatalf("invalid updatable fields: %v", updatable)
+	}
+	if !cmp.Equal(immutable, []string{"AckPolicy", "DeliverPolicy"}) {
+		t.Fatalf("invalid immutable fields: %v", immutable)
+	}
+
+	desired.Durable = "OTHER"
+	_, _, err = c.UpdatableDiff(desired)
+	if err == nil {
+		t.Fatalf("expected an error for another consumer")
+	}
+
+	// server metadata keys and a single filter set in FilterSubjects are not differences
+	rmgr, err := jsm.New(nc, jsm.WithReservedConsumerMetadata())
+	checkErr(t, err, "manager failed")
+	c, err = rmgr.NewConsumer("ORDERS", jsm.DurableName("C2"), jsm.FilterStreamBySubject("ORDERS.new"), jsm.ConsumerMetadata(map[string]string{"team": "orders", "_nats.level": "1"}))
+	checkErr(t, err, "create failed")
+
+	desired = api.ConsumerConfig{Durable: "C2", FilterSubjects: []string{"ORDERS.new"}, Metadata: map[string]string{"team": "orders"}}
+	updatable, immutable, err = c.UpdatableDiff(desired)
+	checkErr(t, err, "diff failed")
+	if len(updatable) > 0 || len(immutable) > 0 {
+		t.Fatalf("expected no differences got %v and %v", updatable, immutable)
+	}
+}
+
+func TestConsumer_EffectiveAckWait(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("PLAIN"), jsm.AckWait(time.Minute))
+	checkErr(t, err, "create failed")
+	if c.EffectiveAckWait(1) != time.Minute || c.EffectiveAckWait(10) != time.Minute {
+		t.Fatalf("expected ack wait without backoff")
+	}
+
+	c, err = mgr.NewConsumer("ORDERS", jsm.DurableName("BACKOFF"), jsm.MaxDeliveryAttempts(5), jsm.BackoffIntervals(time.Second, 2*time.Second, 3*time.Second))
+	checkErr(t, err, "create failed")
+
+	for i, expected := range []time.Duration{time.Second, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if wait := c.EffectiveAckWait(i); wait != expected {
+			t.Fatalf("expected %v for delivery %d got %v", expected, i, wait)
+		}
+	}
+}
+
+func TestConsumer_AckWaitRemaining(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"), jsm.AckWait(time.Minute))
+	checkErr(t, err, "create failed")
+
+	msg, err := c.NextMsg()
+	checkErr(t, err, "next failed")
+	received := time.Now()
+
+	remaining, err := c.AckWaitRemaining(msg, received)
+	checkErr(t, err, "remaining failed")
+	if remaining <= 50*time.Second || remaining > time.Minute {
+		t.Fatalf("expected close to a minute remaining got %v", remaining)
+	}
+
+	// the stored time is long ago but the message was only just received
+	old := nats.NewMsg("x")
+	old.Reply = fmt.Sprintf("$JS.ACK.ORDERS.C1.1.1.1.%d.0", time.Now().Add(-time.Hour).UnixNano())
+	remaining, err = c.AckWaitRemaining(old, received)
+	checkErr(t, err, "remaining failed")
+	if remaining <= 50*time.Second || remaining > time.Minute {
+		t.Fatalf("expected close to a minute remaining got %v", remaining)
+	}
+
+	remaining, err = c.AckWaitRemaining(old, time.Now().Add(-2*time.Minute))
+	checkErr(t, err, "remaining failed")
+	if remaining != 0 {
+		t.Fatalf("expected no time remaining got %v", remaining)
+	}
+
+	_, err = c.AckWaitRemaining(old, time.Time{})
+	if err == nil {
+		t.Fatalf("expected a missing receive time to fail")
+	}
+
+	old.Reply = fmt.Sprintf("$JS.ACK.ORDERS.OTHER.1.1.1.%d.0", time.Now().UnixNano())
+	_, err = c.AckWaitRemaining(old, received)
+	if err == nil {
+		t.Fatalf("expected messages from other consumers to fail")
+	}
+
+	_, err = c.AckWaitRemaining(nats.NewMsg("x"), received)
+	if err == nil {
+		t.Fatalf("expected messages without metadata to fail")
+	}
+}
+
+func TestConsumer_StreamGaps(t *testing.T) {
+	srv, nc, stream, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for i := 2; i <= 10; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	gaps, err := c.StreamGaps(context.Background())
+	checkErr(t, err, "gaps failed")
+	if len(gaps) != 0 {
+		t.Fatalf("expected no gaps got %v", gaps)
+	}
+
+	for _, seq := range []uint64{1, 2, 5, 6, 7, 9} {
+		checkErr(t, stream.DeleteMessage(seq), "delete failed")
+	}
+
+	gaps, err = c.StreamGaps(context.Background())
+	checkErr(t, err, "gaps failed")
+	expected := []jsm.SequenceRange{{Start: 1, End: 2}, {Start: 5, End: 7}, {Start: 9, End: 9}}
+	if !cmp.Equal(gaps, expected) {
+		t.Fatalf("expected %v got %v", expected, gaps)
+	}
+}
+
+func TestPriorityOverflow(t *testing.T) {
+	cfg := testConsumerConfig()
+	err := jsm.PriorityOverflow(0, 0)(cfg)
+	if err == nil || err.Error() != "at least one priority overflow threshold is required" {
+		t.Fatalf("expected threshold error got: %v", err)
+	}
+
+	// the thresholds are not stored by the server so are kept in the metadata
+	checkErr(t, jsm.PriorityOverflow(10, 0)(cfg), "option failed")
+	if cfg.PriorityPolicy != api.PriorityOverflow || cfg.Metadata[jsm.PriorityMinPendingMetadataKey] != "10" {
+		t.Fatalf("expected thresholds in metadata: %#v", cfg)
+	}
+	if _, ok := cfg.Metadata[jsm.PriorityMinAckPendingMetadataKey]; ok {
+		t.Fatalf("expected no min ack pending threshold: %v", cfg.Metadata)
+	}
+
+	_, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.ConsumerPriorityGroups("jobs"), jsm.PriorityOverflow(10, 0))
+	checkErr(t, err, "configuration failed")
+
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.DurableName("P"), jsm.PriorityOverflow(10, 5))
+	if err == nil || err.Error() != "priority overflow thresholds require a priority group" {
+		t.Fatalf("expected priority group error got: %v", err)
+	}
+
+	// the test server does not support priority groups so the consumer api is faked
+	var mu sync.Mutex
+	var stored api.ConsumerConfig
+	var pulls []api.JSApiConsumerGetNextRequest
+
+	_, err = nc.Subscribe("FAKE.CONSUMER.CREATE.ORDERS.P", func(msg *nats.Msg) {
+		var req api.JSApiConsumerCreateRequest
+		json.Unmarshal(msg.Data, &req)
+
+		mu.Lock()
+		stored = req.Config
+		mu.Unlock()
+
+		resp, _ := json.Marshal(api.JSApiConsumerCreateResponse{JSApiResponse: api.JSApiResponse{Type: "io.nats.jetstream.api.v1.consumer_create_response"}, ConsumerInfo: &api.ConsumerInfo{Stream: "ORDERS", Name: "P", Config: req.Config}})
+		msg.Respond(resp)
+	})
+	checkErr(t, err, "subscribe failed")
+
+	_, err = nc.Subscribe("FAKE.CONSUMER.INFO.ORDERS.P", func(msg *nats.Msg) {
+		mu.Lock()
+		cfg := stored
+		mu.Unlock()
+
+		resp, _ := json.Marshal(api.JSApiConsumerInfoResponse{JSApiResponse: api.JSApiResponse{Type: "io.nats.jetstream.api.v1.consumer_info_response"}, ConsumerInfo: &api.ConsumerInfo{Stream: "ORDERS", Name: "P", Config: cfg}})
+		msg.Respond(resp)
+	})
+	checkErr(t, err, "subscribe failed")
+
+	_, err = nc.Subscribe("FAKE.CONSUMER.MSG.NEXT.ORDERS.P", func(msg *nats.Msg) {
+		var req api.JSApiConsumerGetNextRequest
+		json.Unmarshal(msg.Data, &req)
+
+		mu.Lock()
+		pulls = append(pulls, req)
+		mu.Unlock()
+
+		status := nats.NewMsg(msg.Reply)
+		status.Header.Set("Status", "404")
+		status.Header.Set("Description", "No Messages")
+		nc.PublishMsg(status)
+	})
+	checkErr(t, err, "subscribe failed")
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+
+	c, err := fake.NewConsumer("ORDERS", jsm.DurableName("P"), jsm.ConsumerPriorityGroups("jobs"), jsm.PriorityOverflow(10, 5))
+	checkErr(t, err, "create failed")
+	if c.PriorityPolicy() != api.PriorityOverflow {
+		t.Fatalf("expected overflow policy got %v", c.PriorityPolicy())
+	}
+
+	pull := func() api.JSApiConsumerGetNextRequest {
+		t.Helper()
+
+		mb, err := c.Fetch(context.Background(), 1, jsm.PullNoWait())
+		checkErr(t, err, "fetch failed")
+		for range mb.Messages() {
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		return pulls[len(pulls)-1]
+	}
+
+	req := pull()
+	if req.Group != "jobs" || req.MinPending != 10 || req.MinAckPending != 5 {
+		t.Fatalf("expected thresholds in pull request got %+v", req)
+	}
+
+	// updates keep the thresholds unless changed
+	checkErr(t, c.UpdateConfiguration(jsm.ConsumerDescription("updated")), "update failed")
+	req = pull()
+	if req.MinPending != 10 || req.MinAckPending != 5 {
+		t.Fatalf("expected thresholds to be kept got %+v", req)
+	}
+
+	checkErr(t, c.UpdateConfiguration(jsm.PriorityOverflow(20, 0)), "update failed")
+	req = pull()
+	if req.MinPending != 20 || req.MinAckPending != 0 {
+		t.Fatalf("expected updated thresholds got %+v", req)
+	}
+
+	// consumers loaded from the server use the thresholds stored in the metadata
+	c, err = fake.LoadConsumer("ORDERS", "P")
+	checkErr(t, err, "load failed")
+	req = pull()
+	if req.Group != "jobs" || req.MinPending != 20 || req.MinAckPending != 0 {
+		t.Fatalf("expected loaded thresholds got %+v", req)
 	}
 }
 
-func TestStartAtTime(t *testing.T) {
-	cfg := testConsumerConfig()
-	s := time.Now().Add(-1 * time.Hour)
-	jsm.StartAtTime(s)(cfg)
-	if cfg.DeliverPolicy != api.DeliverByStartTime || cfg.OptStartTime.Unix() != s.Unix() {
-		t.Fatal("expected 1 hour delta")
+func TestConsumerPriorityGroups(t *testing.T) {
+	cfg := testConsumerConfig()
+	err := jsm.ConsumerPriorityGroups()(cfg)
+	if err == nil || err.Error() != "at least one priority group is required" {
+		t.Fatalf("expected groups error got: %v", err)
+	}
+
+	err = jsm.ConsumerPriorityGroups("jobs", "not a group")(cfg)
+	if err == nil || err.Error() != `"not a group" is not a valid priority group name` {
+		t.Fatalf("expected invalid group error got: %v", err)
+	}
+
+	err = jsm.ConsumerPriorityPolicy(api.PriorityPolicy(10))(cfg)
+	if err == nil || err.Error() != "unknown priority policy 10" {
+		t.Fatalf("expected unknown policy error got: %v", err)
+	}
+
+	checkErr(t, jsm.ConsumerPriorityGroups("jobs")(cfg), "option failed")
+	checkErr(t, jsm.ConsumerPriorityPolicy(api.PriorityPinnedClient)(cfg), "option failed")
+	if !cmp.Equal(cfg.PriorityGroups, []string{"jobs"}) || cfg.PriorityPolicy != api.PriorityPinnedClient {
+		t.Fatalf("invalid configuration: %+v", cfg)
+	}
+
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.DurableName("P"), jsm.ConsumerPriorityPolicy(api.PriorityPinnedClient))
+	if err == nil || err.Error() != "priority policy Pinned Client requires at least one priority group" {
+		t.Fatalf("expected priority group error got: %v", err)
+	}
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("P"))
+	checkErr(t, err, "create failed")
+	if len(c.PriorityGroups()) != 0 || c.PriorityPolicy() != api.PriorityNone {
+		t.Fatalf("expected no priority settings: %+v", c.Configuration())
 	}
 }
 
-func TestStartAtTimeDelta(t *testing.T) {
+func TestInactiveThreshold(t *testing.T) {
 	cfg := testConsumerConfig()
-	jsm.StartAtTimeDelta(time.Hour)(cfg)
-	if cfg.DeliverPolicy != api.DeliverByStartTime || cfg.OptStartTime.Unix() < time.Now().Add(-1*time.Hour-time.Second).Unix() || cfg.OptStartTime.Unix() > time.Now().Add(-1*time.Hour+time.Second).Unix() {
-		t.Fatal("expected ~ 1 hour delta")
+	err := jsm.InactiveThreshold(-1 * time.Minute)(cfg)
+	if err == nil || err.Error() != "inactive threshold must be positive" {
+		t.Fatalf("expected positive threshold error: %v", err)
 	}
-}
 
-func TestDeliverLastPerSubject(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.DeliverLastPerSubject()(cfg)
-	if cfg.DeliverPolicy != api.DeliverLastPerSubject {
-		t.Fatal("expected DeliverLastPerSubject")
+	err = jsm.InactiveThreshold(time.Minute)(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-}
 
-func TestStartWithLastReceived(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.StartWithLastReceived()(cfg)
-	if cfg.DeliverPolicy != api.DeliverLast {
-		t.Fatal("expected DeliverLast")
+	if cfg.InactiveThreshold != time.Minute {
+		t.Fatalf("expected 1 minute threshold: %v", cfg.InactiveThreshold)
 	}
 }
 
-func TestStartWithNextReceived(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.StartWithNextReceived()(cfg)
-	if cfg.DeliverPolicy != api.DeliverNew {
-		t.Fatal("expected DeliverNew")
+func TestDeliveryGuarantee(t *testing.T) {
+	cfg, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.DeliveryGuarantee(jsm.AtMostOnce))
+	checkErr(t, err, "config failed")
+	if cfg.AckPolicy != api.AckNone || cfg.MaxDeliver != 1 {
+		t.Fatalf("invalid at most once config: %+v", cfg)
 	}
-}
 
-func TestRateLimitBitPerSecond(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.RateLimitBitsPerSecond(10)(cfg)
-	if cfg.RateLimit != 10 {
-		t.Fatal("expected RateLimit==10")
+	cfg, err = jsm.NewConsumerConfiguration(api.ConsumerConfig{}, jsm.DeliveryGuarantee(jsm.AtLeastOnce))
+	checkErr(t, err, "config failed")
+	if cfg.AckPolicy != api.AckExplicit || cfg.MaxDeliver != -1 || cfg.AckWait != 30*time.Second {
+		t.Fatalf("invalid at least once config: %+v", cfg)
 	}
-}
 
-func TestMaxAckOutstanding(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.MaxAckPending(10)(cfg)
-	if cfg.MaxAckPending != 10 {
-		t.Fatal("expected MaxAckPending==10")
+	cfg, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.DeliveryGuarantee(jsm.AtLeastOnce), jsm.MaxDeliveryAttempts(5))
+	checkErr(t, err, "config failed")
+	if cfg.MaxDeliver != 5 {
+		t.Fatalf("expected max deliver 5 got %d", cfg.MaxDeliver)
 	}
-}
 
-func TestIdleHeartbeat(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.IdleHeartbeat(time.Second)(cfg)
-	if cfg.Heartbeat != time.Second {
-		t.Fatalf("expected Heartbeat==1s")
+	_, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.DeliveryGuarantee(jsm.AtLeastOnce), jsm.AcknowledgeNone())
+	if err == nil {
+		t.Fatalf("expected disabling acknowledgements to fail")
 	}
-}
 
-func TestPushFlowControl(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.PushFlowControl()(cfg)
-	if !cfg.FlowControl {
-		t.Fatalf("expected FlowControl==true")
+	_, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.DeliveryGuarantee(jsm.AtMostOnce), jsm.AcknowledgeExplicit())
+	if err == nil {
+		t.Fatalf("expected enabling acknowledgements to fail")
 	}
-}
 
-func TestDeliverGroup(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.DeliverGroup("bob")(cfg)
-	if cfg.DeliverGroup != "bob" {
-		t.Fatalf("expected DeliverGroup==bob")
+	err = jsm.DeliveryGuarantee("exactly_once")(testConsumerConfig())
+	if err == nil {
+		t.Fatalf("expected unknown guarantees to fail")
 	}
 }
 
-func TestMaxWaiting(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.MaxWaiting(10)(cfg)
-	if cfg.MaxWaiting != 10 {
-		t.Fatalf("expected MaxWaiting==10")
+func TestStartAtCalendarBoundary(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
 	}
-}
 
-func TestDeliverHeadersOnly(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.DeliverHeadersOnly()(cfg)
-	if !cfg.HeadersOnly {
-		t.Fatalf("expected headers only to be set")
+	check := func(unit jsm.CalendarUnit, loc *time.Location, valid func(start time.Time, now time.Time) bool) {
+		t.Helper()
+
+		cfg := testConsumerConfig()
+		checkErr(t, jsm.StartAtCalendarBoundary(unit, loc)(cfg), "option failed")
+		now := time.Now()
+
+		if cfg.DeliverPolicy != api.DeliverByStartTime || cfg.OptStartTime == nil {
+			t.Fatalf("expected a start time policy: %+v", cfg)
+		}
+
+		start := *cfg.OptStartTime
+		if loc != nil {
+			start = start.In(loc)
+			now = now.In(loc)
+		}
+
+		if start.After(now) || !valid(start, now) {
+			t.Fatalf("invalid boundary %v for %v", start, now)
+		}
 	}
-}
 
-func TestMaxRequestBatch(t *testing.T) {
-	cfg := testConsumerConfig()
-	jsm.MaxRequestBatch(10)(cfg)
-	if cfg.MaxRequestBatch != 10 {
-		t.Fatalf("expected max request batch of 10: %v", cfg.MaxRequestBatch)
+	check(jsm.CalendarHour, nil, func(start time.Time, now time.Time) bool {
+		return start.Minute() == 0 && start.Second() == 0 && now.Sub(start) < time.Hour
+	})
+	check(jsm.CalendarDay, loc, func(start time.Time, now time.Time) bool {
+		return start.Hour() == 0 && start.Minute() == 0 && start.Day() == now.Day()
+	})
+	check(jsm.CalendarWeek, loc, func(start time.Time, now time.Time) bool {
+		return start.Hour() == 0 && start.Weekday() == time.Monday && now.Sub(start) < 8*24*time.Hour
+	})
+	check(jsm.CalendarMonth, loc, func(start time.Time, now time.Time) bool {
+		return start.Hour() == 0 && start.Day() == 1 && start.Month() == now.Month()
+	})
+
+	err = jsm.StartAtCalendarBoundary(jsm.CalendarUnit(10), nil)(testConsumerConfig())
+	if err == nil {
+		t.Fatalf("expected unknown units to fail")
 	}
 }
 
-func TestMaxRequestExpires(t *testing.T) {
-	cfg := testConsumerConfig()
-	err := jsm.MaxRequestExpires(10 * time.Microsecond)(cfg)
-	if err == nil || err.Error() != "must be larger than 1ms" {
-		t.Fatalf("expected 1ms error got: %v", err)
+func TestConsumerOverrideReplicas(t *testing.T) {
+	for _, r := range []int{0, 1, 3, 5} {
+		cfg := testConsumerConfig()
+		checkErr(t, jsm.ConsumerOverrideReplicas(r)(cfg), "override failed")
+		checkErr(t, jsm.ConsumerReplicasValidated(r)(cfg), "validated failed")
+		if cfg.Replicas != r {
+			t.Fatalf("expected %d replicas got %d", r, cfg.Replicas)
+		}
 	}
 
-	err = jsm.MaxRequestExpires(time.Minute)(cfg)
-	if err != nil {
-		t.Fatalf("exptected no error: %v", err)
+	for _, r := range []int{2, 4} {
+		if jsm.ConsumerOverrideReplicas(r)(testConsumerConfig()) == nil {
+			t.Fatalf("expected %d replicas to fail", r)
+		}
 	}
 
-	if cfg.MaxRequestExpires != time.Minute {
-		t.Fatalf("expected max request expires of 1 minute: %v", cfg.MaxRequestExpires)
+	for _, r := range []int{-1, 2, 6, 7} {
+		if jsm.ConsumerReplicasValidated(r)(testConsumerConfig()) == nil {
+			t.Fatalf("expected %d validated replicas to fail", r)
+		}
 	}
 }
 
-func TestInactiveThreshold(t *testing.T) {
-	cfg := testConsumerConfig()
-	err := jsm.InactiveThreshold(-1 * time.Minute)(cfg)
-	if err == nil || err.Error() != "inactive threshold must be positive" {
-		t.Fatalf("expected positive threshold error: %v", err)
+func TestInactiveThresholdMultiple(t *testing.T) {
+	err := jsm.InactiveThresholdMultiple(0)(testConsumerConfig())
+	if err == nil {
+		t.Fatalf("expected zero multiple to fail")
 	}
 
-	err = jsm.InactiveThreshold(time.Minute)(cfg)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	cfg, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.InactiveThresholdMultiple(2.5), jsm.AckWait(time.Minute))
+	checkErr(t, err, "config failed")
+	if cfg.InactiveThreshold != 150*time.Second {
+		t.Fatalf("expected 150s threshold: %v", cfg.InactiveThreshold)
 	}
 
-	if cfg.InactiveThreshold != time.Minute {
-		t.Fatalf("expected 1 minute threshold: %v", cfg.InactiveThreshold)
+	_, err = jsm.NewConsumerConfiguration(api.ConsumerConfig{}, jsm.InactiveThresholdMultiple(2))
+	if err == nil {
+		t.Fatalf("expected a missing ack wait to fail")
+	}
+
+	// an explicit threshold replaces the multiple
+	cfg, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.InactiveThresholdMultiple(2.5), jsm.InactiveThreshold(time.Hour))
+	checkErr(t, err, "config failed")
+	if cfg.InactiveThreshold != time.Hour || cfg.Metadata[jsm.InactiveThresholdMultipleMetadataKey] != "" {
+		t.Fatalf("expected 1h threshold without a multiple: %v %v", cfg.InactiveThreshold, cfg.Metadata)
 	}
 }
 
@@ -1001,6 +2939,93 @@ func TestLinearBackoffPolicy(t *testing.T) {
 		t.Fatalf("invalid backoff %v expected %v", c.Backoff(), expected)
 	}
 }
+
+func TestExponentialBackoffPolicy(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	s, err := mgr.NewStream("m1", jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	c, err := s.NewConsumer(jsm.ExponentialBackoffPolicy(6, time.Second, 20*time.Second), jsm.DurableName("X"), jsm.MaxDeliveryAttempts(7))
+	checkErr(t, err, "create failed")
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 20 * time.Second}
+	if !cmp.Equal(c.Backoff(), expected) {
+		t.Fatalf("invalid backoff %v expected %v", c.Backoff(), expected)
+	}
+
+	_, err = jsm.ExponentialBackoffPeriods(0, time.Second, time.Minute)
+	if err == nil {
+		t.Fatalf("expected steps error")
+	}
+
+	_, err = jsm.ExponentialBackoffPeriods(5, time.Minute, time.Second)
+	if err == nil {
+		t.Fatalf("expected min > max error")
+	}
+
+	_, err = jsm.ExponentialBackoffPeriods(5, 400*time.Microsecond, time.Second)
+	if err == nil || err.Error() != "minimum retry must be at least 1ms" {
+		t.Fatalf("expected sub millisecond minimum error got %v", err)
+	}
+
+	p, err := jsm.ExponentialBackoffPeriods(100, time.Second, time.Duration(math.MaxInt64))
+	checkErr(t, err, "periods failed")
+	for i := 1; i < len(p); i++ {
+		if p[i] < p[i-1] {
+			t.Fatalf("periods overflowed at step %d: %v", i, p)
+		}
+	}
+}
+
+func TestJitteredBackoffPolicy(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	s, err := mgr.NewStream("m1", jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	base := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+
+	c, err := s.NewConsumer(jsm.JitteredBackoffPolicy(base, 0.25, 10), jsm.DurableName("X"), jsm.MaxDeliveryAttempts(5))
+	checkErr(t, err, "create failed")
+
+	expected, err := jsm.BackoffPeriodsWithJitter(base, 0.25, 10)
+	checkErr(t, err, "periods failed")
+	if !cmp.Equal(c.Backoff(), expected) {
+		t.Fatalf("invalid backoff %v expected %v", c.Backoff(), expected)
+	}
+
+	jittered := false
+	for i, p := range expected {
+		if p < base[i]*3/4 || p > base[i]*5/4 {
+			t.Fatalf("period %v is not within 25%% of %v", p, base[i])
+		}
+		if p != base[i] {
+			jittered = true
+		}
+	}
+	if !jittered {
+		t.Fatalf("expected jitter to be applied: %v", expected)
+	}
+
+	p, err := jsm.BackoffPeriodsWithJitter(base, 0, 10)
+	checkErr(t, err, "periods failed")
+	if !cmp.Equal(p, base) {
+		t.Fatalf("expected no jitter got %v", p)
+	}
+
+	for _, f := range []float64{-0.1, 1, math.NaN()} {
+		_, err = jsm.BackoffPeriodsWithJitter(base, f, 10)
+		if err == nil {
+			t.Fatalf("expected fraction %v to be rejected", f)
+		}
+	}
+}
+
 func TestConsumerDescription(t *testing.T) {
 	srv, nc, mgr := startJSServer(t)
 	defer srv.Shutdown()
@@ -1020,3 +3045,178 @@ func TestConsumerDescription(t *testing.T) {
 		t.Fatalf("invalid description %q", c.Description())
 	}
 }
+
+func TestManager_DryRunConsumer(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	cfg, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.DurableName("C1"), jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "config failed")
+
+	issues, err := mgr.DryRunConsumer("ORDERS", *cfg)
+	checkErr(t, err, "dry run failed")
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues got %v", issues)
+	}
+
+	known, err := mgr.IsKnownConsumer("ORDERS", "C1")
+	checkErr(t, err, "known check failed")
+	if known {
+		t.Fatalf("expected the consumer to not be created")
+	}
+
+	bad := *cfg
+	bad.FilterSubject = "OTHER.>"
+	bad.HeadersOnly = true
+	bad.FlowControl = true
+	issues, err = mgr.DryRunConsumer("ORDERS", bad)
+	checkErr(t, err, "dry run failed")
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues got %v", issues)
+	}
+
+	_, err = mgr.NewConsumerFromDefault("ORDERS", *cfg)
+	checkErr(t, err, "create failed")
+
+	update := *cfg
+	update.Description = "updated"
+	update.AckPolicy = api.AckAll
+	issues, err = mgr.DryRunConsumer("ORDERS", update)
+	checkErr(t, err, "dry run failed")
+	if len(issues) != 1 || !strings.Contains(issues[0], "AckPolicy cannot be changed") {
+		t.Fatalf("expected an ack policy issue got %v", issues)
+	}
+
+	_, err = mgr.NewStream("LIMITED", jsm.Subjects("LIMITED"), jsm.MemoryStorage(), jsm.MaxConsumers(1))
+	checkErr(t, err, "create failed")
+	_, err = mgr.NewConsumer("LIMITED", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	cfg, err = jsm.NewConsumerConfiguration(jsm.DefaultConsumer, jsm.DurableName("C2"))
+	checkErr(t, err, "config failed")
+	issues, err = mgr.DryRunConsumer("LIMITED", *cfg)
+	checkErr(t, err, "dry run failed")
+	if len(issues) != 1 || !strings.Contains(issues[0], "limit of 1 consumers") {
+		t.Fatalf("expected a limit issue got %v", issues)
+	}
+
+	_, err = mgr.DryRunConsumer("MISSING", *cfg)
+	if err == nil {
+		t.Fatalf("expected missing streams to fail")
+	}
+}
+
+func TestManager_NewConsumerNoName(t *testing.T) {
+	srv, nc, _ := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	sub, err := nc.Subscribe("FAKE.CONSUMER.CREATE.>", func(msg *nats.Msg) {
+		msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_create_response"}`))
+	})
+	checkErr(t, err, "subscribe failed")
+	defer sub.Unsubscribe()
+
+	mgr, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+
+	_, err = mgr.NewConsumer("ORDERS", jsm.DurableName("X"))
+	if !errors.Is(err, jsm.ErrNoConsumerName) {
+		t.Fatalf("expected ErrNoConsumerName got %v", err)
+	}
+	if !strings.Contains(err.Error(), `stream ORDERS name "X" durable "X"`) {
+		t.Fatalf("expected the request details in the error got %v", err)
+	}
+}
+
+func TestConsumerLag(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	for i := 2; i <= 5; i++ {
+		_, err := nc.Request("ORDERS.new", []byte(fmt.Sprintf("order %d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	primary, err := mgr.NewConsumer("ORDERS", jsm.DurableName("PRIMARY"))
+	checkErr(t, err, "create failed")
+	standby, err := mgr.NewConsumer("ORDERS", jsm.DurableName("STANDBY"))
+	checkErr(t, err, "create failed")
+
+	consume := func(c *jsm.Consumer, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			msg, err := c.NextMsg()
+			checkErr(t, err, "next failed")
+			_, err = nc.Request(msg.Reply, api.AckAck, time.Second)
+			checkErr(t, err, "ack failed")
+		}
+	}
+
+	consume(primary, 4)
+	consume(standby, 1)
+
+	lag, err := jsm.ConsumerLag(primary, standby)
+	checkErr(t, err, "lag failed")
+	if lag != 3 {
+		t.Fatalf("expected lag 3 got %d", lag)
+	}
+
+	lag, err = jsm.ConsumerLag(standby, primary)
+	checkErr(t, err, "lag failed")
+	if lag != -3 {
+		t.Fatalf("expected lag -3 got %d", lag)
+	}
+
+	filtered, err := mgr.NewConsumer("ORDERS", jsm.DurableName("FILTERED"), jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "create failed")
+	_, err = jsm.ConsumerLag(primary, filtered)
+	if err == nil {
+		t.Fatalf("expected different filters to fail")
+	}
+
+	_, err = mgr.NewStream("OTHER", jsm.Subjects("OTHER"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+	other, err := mgr.NewConsumer("OTHER", jsm.DurableName("PRIMARY"))
+	checkErr(t, err, "create failed")
+	_, err = jsm.ConsumerLag(primary, other)
+	if err == nil {
+		t.Fatalf("expected different streams to fail")
+	}
+}
+
+func TestConsumer_CreateConflictErrors(t *testing.T) {
+	srv, nc, _, mgr := setupConsumerTest(t)
+	defer srv.Shutdown()
+	defer nc.Flush()
+
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+
+	err = c.UpdateConfiguration(jsm.AcknowledgeAll())
+	if !errors.Is(err, jsm.ErrConsumerExistsDifferentConfig) {
+		t.Fatalf("expected ErrConsumerExistsDifferentConfig got %v", err)
+	}
+	if !jsm.IsNatsError(err, 10012) {
+		t.Fatalf("expected the server error to be wrapped got %v", err)
+	}
+
+	err = c.UpdateConfiguration(jsm.ConsumerDescription("updated"))
+	checkErr(t, err, "update failed")
+
+	sub, err := nc.Subscribe("FAKE.CONSUMER.CREATE.>", func(msg *nats.Msg) {
+		msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.consumer_create_response","error":{"code":400,"err_code":10013,"description":"consumer name already in use"}}`))
+	})
+	checkErr(t, err, "subscribe failed")
+	defer sub.Unsubscribe()
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+
+	_, err = fake.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	if !errors.Is(err, jsm.ErrConsumerNameInUse) || !jsm.IsNatsError(err, 10013) {
+		t.Fatalf("expected ErrConsumerNameInUse got %v", err)
+	}
+}
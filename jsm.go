@@ -17,8 +17,12 @@ package jsm
 //go:generate go run api/gen.go
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"strings"
 	"time"
 
@@ -154,14 +158,54 @@ func ParsePubAck(m *nats.Msg) (*api.PubAck, error) {
 	return &res, nil
 }
 
-// IsNatsError checks if err is a ApiErr matching code
+var (
+	// ErrConsumerNotFound matches errors the server returns when a consumer does not exist using errors.Is()
+	ErrConsumerNotFound error = api.ApiError{Code: 404, ErrCode: 10014, Description: "consumer not found"}
+	// ErrStreamNotFound matches errors the server returns when a stream does not exist using errors.Is()
+	ErrStreamNotFound error = api.ApiError{Code: 404, ErrCode: 10059, Description: "stream not found"}
+)
+
+// IsConsumerNotFoundErr determines if err is, or wraps, an error indicating a consumer does not exist
+func IsConsumerNotFoundErr(err error) bool {
+	return errors.Is(err, ErrConsumerNotFound)
+}
+
+// IsStreamNotFoundErr determines if err is, or wraps, an error indicating a stream does not exist
+func IsStreamNotFoundErr(err error) bool {
+	return errors.Is(err, ErrStreamNotFound)
+}
+
+// IsNatsError checks if err is, or wraps, a ApiErr matching code
 func IsNatsError(err error, code uint16) bool {
-	if ae, ok := err.(*api.ApiError); ok {
+	var pae *api.ApiError
+	if errors.As(err, &pae) {
+		return pae.NatsErrorCode() == code
+	}
+
+	var ae api.ApiError
+	if errors.As(err, &ae) {
 		return ae.NatsErrorCode() == code
 	}
 
+	return false
+}
+
+// isRetryableError determines if err is likely transient, like a missing leader during elections, and the request can be retried
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, nats.ErrNoResponders) || errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	if ae, ok := err.(*api.ApiError); ok {
+		return ae.ServerError()
+	}
+
 	if ae, ok := err.(api.ApiError); ok {
-		return ae.NatsErrorCode() == code
+		return ae.ServerError()
 	}
 
 	return false
@@ -215,3 +259,66 @@ func LinearBackoffPeriods(steps uint, min time.Duration, max time.Duration) ([]t
 
 	return res, nil
 }
+
+// ExponentialBackoffPeriods creates a backoff policy without any jitter suitable for use in a consumer backoff policy
+//
+// The periods start from min, which must be at least 1ms as periods are rounded to the millisecond, and double every
+// step until they are capped at max
+func ExponentialBackoffPeriods(steps uint, min time.Duration, max time.Duration) ([]time.Duration, error) {
+	if steps == 0 {
+		return nil, fmt.Errorf("steps must be more than 0")
+	}
+	if min < time.Millisecond {
+		return nil, fmt.Errorf("minimum retry must be at least 1ms")
+	}
+	if max < min {
+		return nil, fmt.Errorf("maximum retry can not be less than minimum retry")
+	}
+
+	res := make([]time.Duration, 0, steps)
+
+	period := min
+	for i := uint(0); i < steps; i++ {
+		res = append(res, period.Round(time.Millisecond))
+
+		if period > max/2 {
+			period = max
+		} else {
+			period *= 2
+		}
+	}
+
+	return res, nil
+}
+
+// BackoffPeriodsWithJitter perturbs every period in base by a random amount of up to fraction of the period in either
+// direction, this spreads out redeliveries of messages that failed at the same time.
+//
+// The random source is seeded with seed so the same inputs always produce the same periods
+func BackoffPeriodsWithJitter(base []time.Duration, fraction float64, seed int64) ([]time.Duration, error) {
+	if len(base) == 0 {
+		return nil, fmt.Errorf("at least one period is required")
+	}
+	if math.IsNaN(fraction) || fraction < 0 || fraction >= 1 {
+		return nil, fmt.Errorf("jitter fraction must be at least 0 and less than 1")
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	res := make([]time.Duration, 0, len(base))
+
+	for _, p := range base {
+		if p <= 0 {
+			return nil, fmt.Errorf("periods must be more than 0")
+		}
+
+		jitter := time.Duration(float64(p) * fraction * (2*r.Float64() - 1))
+		period := (p + jitter).Round(time.Millisecond)
+		if period < time.Millisecond {
+			period = time.Millisecond
+		}
+
+		res = append(res, period)
+	}
+
+	return res, nil
+}
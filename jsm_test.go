@@ -15,6 +15,7 @@ package jsm_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -145,3 +146,26 @@ func TestIsNatsError(t *testing.T) {
 		t.Fatalf("Non api error is 10077")
 	}
 }
+
+func TestNotFoundErrors(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err := mgr.LoadStream("MISSING")
+	if !errors.Is(err, jsm.ErrStreamNotFound) || !jsm.IsStreamNotFoundErr(err) || jsm.IsConsumerNotFoundErr(err) {
+		t.Fatalf("expected stream not found got %v", err)
+	}
+
+	_, err = mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = mgr.LoadConsumer("ORDERS", "MISSING")
+	if !errors.Is(err, jsm.ErrConsumerNotFound) || !jsm.IsConsumerNotFoundErr(fmt.Errorf("wrapped: %w", err)) || jsm.IsStreamNotFoundErr(err) {
+		t.Fatalf("expected consumer not found got %v", err)
+	}
+
+	if jsm.IsConsumerNotFoundErr(fmt.Errorf("consumer not found")) || jsm.IsConsumerNotFoundErr(nil) {
+		t.Fatalf("expected non api errors to not match")
+	}
+}
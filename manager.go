@@ -38,6 +38,12 @@ type Manager struct {
 	eventPrefix string
 	domain      string
 
+	filterCoverageCheck      bool
+	consumerMetadata         map[string]string
+	minInactiveThreshold     time.Duration
+	consumerMetadataLimit    int
+	reservedConsumerMetadata bool
+
 	sync.Mutex
 }
 
@@ -59,6 +65,10 @@ func New(nc *nats.Conn, opts ...Option) (*Manager, error) {
 		m.timeout = 500 * time.Millisecond
 	}
 
+	if m.consumerMetadataLimit < 0 || m.consumerMetadataLimit > maxMetadataBytes {
+		return nil, fmt.Errorf("consumer metadata limit must be between 0 and %d bytes", maxMetadataBytes)
+	}
+
 	return m, nil
 }
 
@@ -90,6 +100,40 @@ func (m *Manager) JetStreamAccountInfo() (info *api.JetStreamAccountStats, err e
 	return resp.JetStreamAccountStats, nil
 }
 
+// WaitForJetStream polls the JetStream account information every poll until it is retrieved, which indicates JetStream
+// is available, or until ctx is cancelled. No responders, timeouts and server errors are treated as JetStream not being
+// ready yet while other errors, like permission errors, are returned immediately
+func (m *Manager) WaitForJetStream(ctx context.Context, poll time.Duration) error {
+	if poll <= 0 {
+		return fmt.Errorf("poll interval must be positive")
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		rctx, cancel := context.WithTimeout(ctx, m.timeout)
+		var resp api.JSApiAccountInfoResponse
+		err := m.jsonRequestWithContext(rctx, api.JSApiAccountInfo, nil, &resp)
+		cancel()
+
+		switch {
+		case err == nil:
+			return nil
+		case ctx.Err() != nil:
+			return fmt.Errorf("jetstream did not become available: %w", ctx.Err())
+		case !isRetryableError(err):
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("jetstream did not become available: %w", ctx.Err())
+		}
+	}
+}
+
 // IsStreamMaxBytesRequired determines if the JetStream account requires streams to set a byte limit
 func (m *Manager) IsStreamMaxBytesRequired() (bool, error) {
 	nfo, err := m.JetStreamAccountInfo()
@@ -111,6 +155,11 @@ func (m *Manager) IsStreamMaxBytesRequired() (bool, error) {
 }
 
 func (m *Manager) jsonRequest(subj string, req any, response any) (err error) {
+	return m.jsonRequestWithContext(context.Background(), subj, req, response)
+}
+
+// jsonRequestWithContext performs a JSON API request interrupted by ctx, when ctx has no deadline the manager timeout is used
+func (m *Manager) jsonRequestWithContext(ctx context.Context, subj string, req any, response any) (err error) {
 	var body []byte
 
 	switch {
@@ -123,7 +172,13 @@ func (m *Manager) jsonRequest(subj string, req any, response any) (err error) {
 		}
 	}
 
-	msg, err := m.request(m.apiSubject(subj), body)
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+
+	msg, err := m.requestWithContext(ctx, m.apiSubject(subj), body)
 	if err != nil {
 		return err
 	}
@@ -271,6 +326,10 @@ func (m *Manager) requestWithTimeout(subj string, data []byte, timeout time.Dura
 }
 
 func (m *Manager) requestWithContext(ctx context.Context, subj string, data []byte) (res *nats.Msg, err error) {
+	if m == nil || m.nc == nil {
+		return nil, fmt.Errorf("nats connection is not set")
+	}
+
 	if m.trace {
 		log.Printf(">>> %s\n%s\n\n", subj, string(data))
 	}
@@ -349,41 +408,123 @@ func (m *Manager) EachStream(filter *StreamNamesFilter, cb func(*Stream)) (missi
 
 // Consumers is a sorted list of all known Consumers within a Stream and a list of any consumer names that were known but no details were found
 func (m *Manager) Consumers(stream string) (consumers []*Consumer, missing []string, err error) {
-	if !IsValidName(stream) {
-		return nil, nil, fmt.Errorf("%q is not a valid stream name", stream)
+	cinfo, missing, err := m.consumerInfos(stream)
+	if err != nil {
+		return consumers, missing, err
 	}
 
-	var (
-		cinfo []*api.ConsumerInfo
-		resp  = func() apiIterableResponse { return &api.JSApiConsumerListResponse{} }
-	)
+	for _, c := range cinfo {
+		consumer := m.consumerFromCfg(c.Stream, c.Name, &c.Config)
+		consumer.lastInfo = c
 
-	err = m.iterableRequest(fmt.Sprintf(api.JSApiConsumerListT, stream), &api.JSApiConsumerListRequest{JSApiIterableRequest: api.JSApiIterableRequest{Offset: 0}}, resp, func(page any) error {
-		apiresp, ok := page.(*api.JSApiConsumerListResponse)
-		if !ok {
-			return fmt.Errorf("invalid response type from iterable request")
+		consumers = append(consumers, consumer)
+	}
+
+	return consumers, missing, nil
+}
+
+// EachConsumer pages through all known Consumers within a Stream calling cb for each as the pages are received, iteration
+// stops on the first error returned by cb. Consumers are passed in the order the server lists them, use Consumers() for
+// a sorted list. Names of consumers that were known but no details were found are returned
+func (m *Manager) EachConsumer(stream string, cb func(*Consumer) error) (missing []string, err error) {
+	if cb == nil {
+		return nil, fmt.Errorf("callback is required")
+	}
+
+	return m.eachConsumerInfo(stream, func(nfo *api.ConsumerInfo) error {
+		consumer := m.consumerFromCfg(nfo.Stream, nfo.Name, &nfo.Config)
+		consumer.lastInfo = nfo
+
+		return cb(consumer)
+	})
+}
+
+// ConsumerConfigs is the configuration of all known Consumers within a Stream sorted by name, metadata keys reserved by the server are removed
+func (m *Manager) ConsumerConfigs(stream string) ([]api.ConsumerConfig, error) {
+	cinfo, _, err := m.consumerInfos(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]api.ConsumerConfig, 0, len(cinfo))
+	for _, c := range cinfo {
+		cfg := c.Config
+		cfg.Metadata = withoutServerMetadata(cfg.Metadata)
+
+		configs = append(configs, cfg)
+	}
+
+	return configs, nil
+}
+
+// StaleFilterConsumers is a sorted list of consumers on stream whose filter subjects do not overlap any of the stream
+// subjects, these consumers will never receive messages. Streams without subjects, like mirrors, are not checked
+func (m *Manager) StaleFilterConsumers(stream string) ([]string, error) {
+	nfo, err := m.loadStreamInfo(stream, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cinfo, _, err := m.consumerInfos(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, c := range cinfo {
+		if validateFilterCoverage(nfo, &c.Config) != nil {
+			stale = append(stale, c.Name)
 		}
+	}
 
-		missing = append(missing, apiresp.Missing...)
-		cinfo = append(cinfo, apiresp.Consumers...)
+	return stale, nil
+}
+
+// consumerInfos pages through the information for all consumers on stream sorted by name
+func (m *Manager) consumerInfos(stream string) (cinfo []*api.ConsumerInfo, missing []string, err error) {
+	missing, err = m.eachConsumerInfo(stream, func(nfo *api.ConsumerInfo) error {
+		cinfo = append(cinfo, nfo)
 		return nil
 	})
 	if err != nil {
-		return consumers, missing, err
+		return nil, missing, err
 	}
 
 	sort.Slice(cinfo, func(i int, j int) bool {
 		return cinfo[i].Name < cinfo[j].Name
 	})
 
-	for _, c := range cinfo {
-		consumer := m.consumerFromCfg(c.Stream, c.Name, &c.Config)
-		consumer.lastInfo = c
+	return cinfo, missing, nil
+}
 
-		consumers = append(consumers, consumer)
+// eachConsumerInfo pages through the information for all consumers on stream calling cb for each in the order they
+// are listed, iteration stops on the first error returned by cb
+func (m *Manager) eachConsumerInfo(stream string, cb func(*api.ConsumerInfo) error) (missing []string, err error) {
+	if !IsValidName(stream) {
+		return nil, fmt.Errorf("%q is not a valid stream name", stream)
 	}
 
-	return consumers, missing, nil
+	resp := func() apiIterableResponse { return &api.JSApiConsumerListResponse{} }
+
+	err = m.iterableRequest(fmt.Sprintf(api.JSApiConsumerListT, stream), &api.JSApiConsumerListRequest{JSApiIterableRequest: api.JSApiIterableRequest{Offset: 0}}, resp, func(page any) error {
+		apiresp, ok := page.(*api.JSApiConsumerListResponse)
+		if !ok {
+			return fmt.Errorf("invalid response type from iterable request")
+		}
+
+		missing = append(missing, apiresp.Missing...)
+
+		for _, nfo := range apiresp.Consumers {
+			err := cb(nfo)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return missing, err
 }
 
 // StreamTemplateNames is a sorted list of all known StreamTemplates
@@ -408,19 +549,27 @@ func (m *Manager) StreamTemplateNames() (templates []string, err error) {
 	return templates, nil
 }
 
-// ConsumerNames is a sorted list of all known consumers within a stream
+// ConsumerNames is a sorted list of all known consumers within a stream, names listed on more than one page when
+// consumers are added or removed while paging are only included once
 func (m *Manager) ConsumerNames(stream string) (names []string, err error) {
 	if !IsValidName(stream) {
 		return nil, fmt.Errorf("%q is not a valid stream name", stream)
 	}
 
+	seen := map[string]bool{}
 	err = m.iterableRequest(fmt.Sprintf(api.JSApiConsumerNamesT, stream), &api.JSApiConsumerNamesRequest{JSApiIterableRequest: api.JSApiIterableRequest{Offset: 0}}, func() apiIterableResponse { return &api.JSApiConsumerNamesResponse{} }, func(page any) error {
 		apiresp, ok := page.(*api.JSApiConsumerNamesResponse)
 		if !ok {
 			return fmt.Errorf("invalid response type from iterable request")
 		}
 
-		names = append(names, apiresp.Consumers...)
+		for _, name := range apiresp.Consumers {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
 
 		return nil
 	})
@@ -433,6 +582,22 @@ func (m *Manager) ConsumerNames(stream string) (names []string, err error) {
 	return names, nil
 }
 
+// ConsumerCount is the number of consumers within a stream, it is read from the total reported with the first
+// page of consumer names so only a single request is made regardless of the number of consumers
+func (m *Manager) ConsumerCount(stream string) (int, error) {
+	if !IsValidName(stream) {
+		return 0, fmt.Errorf("%q is not a valid stream name", stream)
+	}
+
+	var resp api.JSApiConsumerNamesResponse
+	err := m.jsonRequest(fmt.Sprintf(api.JSApiConsumerNamesT, stream), &api.JSApiConsumerNamesRequest{JSApiIterableRequest: api.JSApiIterableRequest{Offset: 0}}, &resp)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.Total, nil
+}
+
 // Streams is a sorted list of all known Streams and a list of any stream names that were known but no details were found
 func (m *Manager) Streams(filter *StreamNamesFilter) ([]*Stream, []string, error) {
 	var (
@@ -516,8 +681,12 @@ func (m *Manager) DeleteConsumer(stream string, consumer string) error {
 		return fmt.Errorf("invalid consumer name")
 	}
 
+	return m.deleteConsumerWithContext(context.Background(), stream, consumer)
+}
+
+func (m *Manager) deleteConsumerWithContext(ctx context.Context, stream string, consumer string) error {
 	var resp api.JSApiConsumerDeleteResponse
-	err := m.jsonRequest(fmt.Sprintf(api.JSApiConsumerDeleteT, stream, consumer), nil, &resp)
+	err := m.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiConsumerDeleteT, stream, consumer), nil, &resp)
 	if err != nil {
 		return err
 	}
@@ -529,6 +698,116 @@ func (m *Manager) DeleteConsumer(stream string, consumer string) error {
 	return nil
 }
 
+// backlogConcurrency is the number of consumer info requests StreamConsumerBacklog makes concurrently
+const backlogConcurrency = 10
+
+// StreamConsumerBacklog sums the messages pending delivery and awaiting acknowledgement across all consumers on
+// stream, perConsumer holds the sum of both for every consumer. Consumers removed while the backlog is calculated
+// are not included
+func (m *Manager) StreamConsumerBacklog(ctx context.Context, stream string) (totalPending uint64, totalAckPending uint64, perConsumer map[string]uint64, err error) {
+	names, err := m.ConsumerNames(stream)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		limiter = make(chan struct{}, backlogConcurrency)
+		ferr    error
+	)
+
+	perConsumer = make(map[string]uint64, len(names))
+
+	for _, name := range names {
+		select {
+		case limiter <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return 0, 0, nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-limiter }()
+
+			nfo, err := m.loadConsumerInfoWithContext(ctx, stream, name)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case IsConsumerNotFoundErr(err):
+			case err != nil:
+				if ferr == nil {
+					ferr = fmt.Errorf("loading consumer %s failed: %w", name, err)
+				}
+			default:
+				totalPending += nfo.NumPending
+				totalAckPending += uint64(nfo.NumAckPending)
+				perConsumer[name] = nfo.NumPending + uint64(nfo.NumAckPending)
+			}
+		}(name)
+	}
+
+	wg.Wait()
+
+	if ferr != nil {
+		return 0, 0, nil, ferr
+	}
+
+	return totalPending, totalAckPending, perConsumer, nil
+}
+
+// DeleteAllConsumers deletes every consumer on stream continuing past failures, errs holds the reason any consumer
+// could not be deleted keyed by consumer name or by the stream name when the consumers could not be listed.
+//
+// When skipBusy is true consumers with messages awaiting acknowledgement are left in place and are not reported in
+// deleted or errs. Once ctx is cancelled no further consumers are deleted and the remaining ones are reported in errs
+func (m *Manager) DeleteAllConsumers(ctx context.Context, stream string, skipBusy bool) (deleted []string, errs map[string]error) {
+	errs = make(map[string]error)
+
+	consumers, missing, err := m.Consumers(stream)
+	if err != nil {
+		errs[stream] = err
+		return nil, errs
+	}
+
+	var names []string
+	for _, c := range consumers {
+		if skipBusy && c.lastInfo != nil && c.lastInfo.NumAckPending > 0 {
+			continue
+		}
+		names = append(names, c.Name())
+	}
+
+	for _, name := range missing {
+		if skipBusy {
+			errs[name] = fmt.Errorf("could not determine if consumer %s has pending acknowledgements", name)
+			continue
+		}
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			errs[name] = ctx.Err()
+			continue
+		}
+
+		err = m.deleteConsumerWithContext(ctx, stream, name)
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+
+		deleted = append(deleted, name)
+	}
+
+	return deleted, errs
+}
+
 // StreamContainedSubjects queries the stream for the subjects it holds with optional filter
 func (m *Manager) StreamContainedSubjects(stream string, filter ...string) (map[string]uint64, error) {
 	if len(filter) > 1 {
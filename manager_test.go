@@ -15,13 +15,17 @@ package jsm_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/nats-io/jsm.go"
 	"github.com/nats-io/jsm.go/api"
 	natsd "github.com/nats-io/nats-server/v2/server"
@@ -237,6 +241,101 @@ func TestJetStreamEnabled(t *testing.T) {
 	}
 }
 
+func TestWaitForJetStream(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	err := mgr.WaitForJetStream(context.Background(), 10*time.Millisecond)
+	checkErr(t, err, "wait failed")
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"), jsm.WithTimeout(100*time.Millisecond))
+	checkErr(t, err, "manager failed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err = fake.WaitForJetStream(ctx, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded got %v", err)
+	}
+
+	time.AfterFunc(100*time.Millisecond, func() {
+		nc.Subscribe("FAKE.INFO", func(msg *nats.Msg) {
+			msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.account_info_response","memory":0}`))
+		})
+	})
+
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err = fake.WaitForJetStream(ctx, 10*time.Millisecond)
+	checkErr(t, err, "wait failed")
+
+	denied, err := jsm.New(nc, jsm.WithAPIPrefix("DENIED"), jsm.WithTimeout(100*time.Millisecond))
+	checkErr(t, err, "manager failed")
+	nc.Subscribe("DENIED.INFO", func(msg *nats.Msg) {
+		msg.Respond([]byte(`{"type":"io.nats.jetstream.api.v1.account_info_response","error":{"code":403,"description":"permission denied"}}`))
+	})
+
+	err = denied.WaitForJetStream(ctx, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected permission error got %v", err)
+	}
+}
+
+func TestManagerOptions(t *testing.T) {
+	srv, nc, _ := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	traced, err := jsm.NewManagerFromOptions(nc, jsm.ManagerOptions{Trace: true})
+	checkErr(t, err, "manager failed")
+	if !traced.Options().Trace {
+		t.Fatalf("expected trace to be enabled")
+	}
+
+	mgr, err := jsm.New(nc, jsm.WithTimeout(2*time.Second), jsm.WithEventPrefix("EVENTS"), jsm.WithFilterCoverageCheck(), jsm.WithDefaultConsumerMetadata(map[string]string{"team": "orders"}), jsm.WithMinInactiveThreshold(time.Minute), jsm.WithConsumerMetadataLimit(1024), jsm.WithReservedConsumerMetadata())
+	checkErr(t, err, "manager failed")
+
+	opts := mgr.Options()
+	if opts.Timeout != 2*time.Second || opts.EventPrefix != "EVENTS" || !opts.FilterCoverageCheck || opts.DefaultConsumerMetadata["team"] != "orders" || opts.MinInactiveThreshold != time.Minute || opts.ConsumerMetadataLimit != 1024 || !opts.ReservedConsumerMetadata {
+		t.Fatalf("invalid options: %+v", opts)
+	}
+
+	j, err := json.Marshal(opts)
+	checkErr(t, err, "marshal failed")
+	var restored jsm.ManagerOptions
+	checkErr(t, json.Unmarshal(j, &restored), "unmarshal failed")
+
+	mgr, err = jsm.NewManagerFromOptions(nc, restored)
+	checkErr(t, err, "manager failed")
+	if !cmp.Equal(mgr.Options(), opts) {
+		t.Fatalf("expected options to be restored: %+v", mgr.Options())
+	}
+
+	_, err = mgr.NewStream("ORDERS", jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+	c, err := mgr.NewConsumer("ORDERS", jsm.DurableName("C1"))
+	checkErr(t, err, "create failed")
+	if c.Metadata()["team"] != "orders" {
+		t.Fatalf("expected default metadata to be set: %v", c.Metadata())
+	}
+	if c.InactiveThreshold() != 0 {
+		t.Fatalf("expected durable inactive threshold to be unchanged: %v", c.InactiveThreshold())
+	}
+
+	c, err = mgr.NewConsumer("ORDERS")
+	checkErr(t, err, "create failed")
+	if c.InactiveThreshold() != time.Minute {
+		t.Fatalf("expected minimum inactive threshold got %v", c.InactiveThreshold())
+	}
+
+	c, err = mgr.NewConsumer("ORDERS", jsm.InactiveThreshold(time.Hour))
+	checkErr(t, err, "create failed")
+	if c.InactiveThreshold() != time.Hour {
+		t.Fatalf("expected larger inactive threshold to be kept got %v", c.InactiveThreshold())
+	}
+}
+
 func TestDeleteStream(t *testing.T) {
 	srv, nc, mgr := startJSServer(t)
 	defer srv.Shutdown()
@@ -294,6 +393,87 @@ func TestDeleteConsumer(t *testing.T) {
 	}
 }
 
+func TestDeleteAllConsumers(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	stream, err := mgr.NewStreamFromDefault("ORDERS", jsm.DefaultStream, jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = nc.Request("ORDERS.new", []byte("1"), time.Second)
+	checkErr(t, err, "publish failed")
+
+	busy, err := stream.NewConsumer(jsm.DurableName("BUSY"))
+	checkErr(t, err, "create failed")
+	_, err = busy.NextMsg()
+	checkErr(t, err, "next failed")
+
+	for _, name := range []string{"C1", "C2"} {
+		_, err = stream.NewConsumer(jsm.DurableName(name))
+		checkErr(t, err, "create failed")
+	}
+
+	deleted, errs := mgr.DeleteAllConsumers(context.Background(), "ORDERS", true)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(deleted) != 2 || deleted[0] != "C1" || deleted[1] != "C2" {
+		t.Fatalf("expected C1 and C2 to be deleted got %v", deleted)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deleted, errs = mgr.DeleteAllConsumers(ctx, "ORDERS", false)
+	if len(deleted) != 0 || errs["BUSY"] != context.Canceled {
+		t.Fatalf("expected cancellation got %v %v", deleted, errs)
+	}
+
+	deleted, errs = mgr.DeleteAllConsumers(context.Background(), "ORDERS", false)
+	if len(errs) != 0 || len(deleted) != 1 || deleted[0] != "BUSY" {
+		t.Fatalf("expected BUSY to be deleted got %v %v", deleted, errs)
+	}
+
+	names, err := stream.ConsumerNames()
+	checkErr(t, err, "names failed")
+	if len(names) != 0 {
+		t.Fatalf("expected no consumers got %v", names)
+	}
+}
+
+func TestStreamConsumerBacklog(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	stream, err := mgr.NewStreamFromDefault("ORDERS", jsm.DefaultStream, jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	for i := 0; i < 5; i++ {
+		_, err = nc.Request("ORDERS.new", []byte(fmt.Sprintf("%d", i)), time.Second)
+		checkErr(t, err, "publish failed")
+	}
+
+	busy, err := stream.NewConsumer(jsm.DurableName("BUSY"))
+	checkErr(t, err, "create failed")
+	for i := 0; i < 2; i++ {
+		_, err = busy.NextMsg()
+		checkErr(t, err, "next failed")
+	}
+
+	_, err = stream.NewConsumer(jsm.DurableName("IDLE"))
+	checkErr(t, err, "create failed")
+
+	pending, ackPending, per, err := mgr.StreamConsumerBacklog(context.Background(), "ORDERS")
+	checkErr(t, err, "backlog failed")
+	if pending != 8 || ackPending != 2 {
+		t.Fatalf("expected 8 pending and 2 ack pending got %d and %d", pending, ackPending)
+	}
+	if len(per) != 2 || per["BUSY"] != 5 || per["IDLE"] != 5 {
+		t.Fatalf("invalid per consumer backlog: %v", per)
+	}
+}
+
 func TestIsKnownStream(t *testing.T) {
 	srv, nc, mgr := startJSServer(t)
 	defer srv.Shutdown()
@@ -469,6 +649,200 @@ func TestConsumerNames(t *testing.T) {
 	}
 }
 
+func TestConsumerNames_Paging(t *testing.T) {
+	srv, nc, _ := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	// C2 moves from the first to the second page as if a consumer was deleted between requests
+	pages := map[int]string{
+		0: `{"type":"io.nats.jetstream.api.v1.consumer_names_response","total":4,"offset":0,"limit":2,"consumers":["C3","C2"]}`,
+		2: `{"type":"io.nats.jetstream.api.v1.consumer_names_response","total":4,"offset":2,"limit":2,"consumers":["C2","C1"]}`,
+	}
+
+	_, err := nc.Subscribe("FAKE.CONSUMER.NAMES.ORDERS", func(msg *nats.Msg) {
+		var req api.JSApiConsumerNamesRequest
+		err := json.Unmarshal(msg.Data, &req)
+		if err != nil {
+			msg.Respond([]byte(`{"error":{"code":400,"description":"invalid request"}}`))
+			return
+		}
+
+		msg.Respond([]byte(pages[req.Offset]))
+	})
+	checkErr(t, err, "subscribe failed")
+
+	fake, err := jsm.New(nc, jsm.WithAPIPrefix("FAKE"))
+	checkErr(t, err, "manager failed")
+
+	names, err := fake.ConsumerNames("ORDERS")
+	checkErr(t, err, "names failed")
+	if !cmp.Equal(names, []string{"C1", "C2", "C3"}) {
+		t.Fatalf("expected [C1 C2 C3] got %v", names)
+	}
+}
+
+func TestEachConsumer(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err := mgr.EachConsumer("ORDERS", nil)
+	if err == nil || err.Error() != "callback is required" {
+		t.Fatalf("expected callback error got %v", err)
+	}
+
+	stream, err := mgr.NewStreamFromDefault("ORDERS", jsm.DefaultStream, jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	// more than fits on a single page of the consumer list
+	for i := 0; i < 300; i++ {
+		_, err = stream.NewConsumerFromDefault(jsm.DefaultConsumer, jsm.DurableName(fmt.Sprintf("C%d", i)))
+		checkErr(t, err, "create failed")
+	}
+
+	seen := map[string]bool{}
+	missing, err := mgr.EachConsumer("ORDERS", func(c *jsm.Consumer) error {
+		if seen[c.Name()] {
+			t.Fatalf("received %s twice", c.Name())
+		}
+		seen[c.Name()] = true
+
+		if c.StreamName() != "ORDERS" || c.DurableName() != c.Name() {
+			t.Fatalf("consumer %s was not hydrated: %+v", c.Name(), c.Configuration())
+		}
+
+		nfo, err := c.LatestState()
+		checkErr(t, err, "state failed")
+		if nfo.Name != c.Name() {
+			t.Fatalf("expected info for %s got %s", c.Name(), nfo.Name)
+		}
+
+		return nil
+	})
+	checkErr(t, err, "each failed")
+	if len(missing) != 0 {
+		t.Fatalf("expected no missing consumers got %v", missing)
+	}
+	if len(seen) != 300 {
+		t.Fatalf("expected 300 consumers got %d", len(seen))
+	}
+
+	consumers, missing, err := mgr.Consumers("ORDERS")
+	checkErr(t, err, "consumers failed")
+	if len(consumers) != 300 || len(missing) != 0 {
+		t.Fatalf("expected 300 consumers got %d missing %v", len(consumers), missing)
+	}
+
+	calls := 0
+	_, err = mgr.EachConsumer("ORDERS", func(c *jsm.Consumer) error {
+		calls++
+		return fmt.Errorf("stop")
+	})
+	if err == nil || err.Error() != "stop" || calls != 1 {
+		t.Fatalf("expected iteration to stop after one call got %d calls and %v", calls, err)
+	}
+}
+
+func TestConsumerCount(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err := mgr.ConsumerCount("in.valid")
+	if err == nil {
+		t.Fatalf("expected an invalid stream name error")
+	}
+
+	stream, err := mgr.NewStreamFromDefault("ORDERS", jsm.DefaultStream, jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	count, err := mgr.ConsumerCount("ORDERS")
+	checkErr(t, err, "count failed")
+	if count != 0 {
+		t.Fatalf("expected 0 consumers got %d", count)
+	}
+
+	// more than fits in a single page of names
+	for i := 0; i < 1030; i++ {
+		_, err = stream.NewConsumer(jsm.DurableName(fmt.Sprintf("C%d", i)), jsm.ConsumerOverrideMemoryStorage())
+		checkErr(t, err, "create failed")
+	}
+
+	count, err = mgr.ConsumerCount("ORDERS")
+	checkErr(t, err, "count failed")
+	if count != 1030 {
+		t.Fatalf("expected 1030 consumers got %d", count)
+	}
+}
+
+func TestConsumerConfigs(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	_, err := mgr.ConsumerConfigs("in.valid")
+	if err == nil {
+		t.Fatalf("expected an invalid stream name error")
+	}
+
+	stream, err := mgr.NewStreamFromDefault("ORDERS", jsm.DefaultStream, jsm.Subjects("ORDERS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = stream.NewConsumer(jsm.DurableName("B"), jsm.ConsumerDescription("b"))
+	checkErr(t, err, "create failed")
+	_, err = stream.NewConsumer(jsm.DurableName("A"), jsm.ConsumerMetadata(map[string]string{"team": "orders"}))
+	checkErr(t, err, "create failed")
+
+	configs, err := mgr.ConsumerConfigs("ORDERS")
+	checkErr(t, err, "configs failed")
+	if len(configs) != 2 || configs[0].Durable != "A" || configs[1].Durable != "B" {
+		t.Fatalf("invalid configs: %+v", configs)
+	}
+	if configs[0].Metadata["team"] != "orders" || configs[1].Description != "b" {
+		t.Fatalf("invalid configs: %+v", configs)
+	}
+}
+
+func TestStaleFilterConsumers(t *testing.T) {
+	srv, nc, mgr := startJSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	stream, err := mgr.NewStreamFromDefault("ORDERS", jsm.DefaultStream, jsm.Subjects("ORDERS.*", "RETURNS.*"), jsm.MemoryStorage())
+	checkErr(t, err, "create failed")
+
+	_, err = stream.NewConsumer(jsm.DurableName("ALL"))
+	checkErr(t, err, "create failed")
+	_, err = stream.NewConsumer(jsm.DurableName("ORDERS"), jsm.FilterStreamBySubject("ORDERS.new"))
+	checkErr(t, err, "create failed")
+	_, err = stream.NewConsumer(jsm.DurableName("RETURNS"), jsm.FilterStreamBySubject("RETURNS.new"))
+	checkErr(t, err, "create failed")
+	_, err = stream.NewConsumer(jsm.DurableName("MIXED"), jsm.FilterStreamBySubject("ORDERS.new", "RETURNS.new"))
+	checkErr(t, err, "create failed")
+
+	stale, err := mgr.StaleFilterConsumers("ORDERS")
+	checkErr(t, err, "stale failed")
+	if len(stale) != 0 {
+		t.Fatalf("expected no stale consumers got %v", stale)
+	}
+
+	cfg := stream.Configuration()
+	cfg.Subjects = []string{"ORDERS.*"}
+	checkErr(t, stream.UpdateConfiguration(cfg), "update failed")
+
+	stale, err = mgr.StaleFilterConsumers("ORDERS")
+	checkErr(t, err, "stale failed")
+	if len(stale) != 1 || stale[0] != "RETURNS" {
+		t.Fatalf("expected RETURNS to be stale got %v", stale)
+	}
+
+	_, err = mgr.StaleFilterConsumers("MISSING")
+	if err == nil {
+		t.Fatalf("expected missing streams to fail")
+	}
+}
+
 func TestEachStream(t *testing.T) {
 	srv, nc, mgr := startJSServer(t)
 	defer srv.Shutdown()
@@ -21,6 +21,8 @@ import (
 	"time"
 
 	"github.com/nats-io/nats.go"
+
+	"github.com/nats-io/jsm.go/api"
 )
 
 // MsgInfo holds metadata about a message that was received from JetStream
@@ -33,6 +35,8 @@ type MsgInfo struct {
 	pending   uint64
 	ts        time.Time
 	domain    string
+	oStream   string
+	oSeq      uint64
 }
 
 // Stream is the stream this message is stored in
@@ -75,6 +79,17 @@ func (i *MsgInfo) Pending() uint64 {
 	return i.pending
 }
 
+// OriginStream is the stream the message was copied from when it was sourced into Stream(), empty when the message
+// was not sourced or the metadata was parsed from only the reply subject
+func (i *MsgInfo) OriginStream() string {
+	return i.oStream
+}
+
+// OriginSequence is the sequence of the message in OriginStream(), 0 when the message was not sourced
+func (i *MsgInfo) OriginSequence() uint64 {
+	return i.oSeq
+}
+
 const _EMPTY_ = ""
 
 // ParseJSMsgMetadataReply parses the reply subject of a JetStream originated message
@@ -117,10 +132,52 @@ func ParseJSMsgMetadataReply(reply string) (info *MsgInfo, err error) {
 		domain = parts[2]
 	}
 
-	return &MsgInfo{stream, consumer, streamSeq, consumerSeq, delivered, pending, ts, domain}, nil
+	return &MsgInfo{stream: stream, consumer: consumer, sSeq: streamSeq, cSeq: consumerSeq, delivered: delivered, pending: pending, ts: ts, domain: domain}, nil
 }
 
-// ParseJSMsgMetadata parse the reply subject metadata to determine message metadata
+// ParseJSMsgMetadata parse the reply subject metadata to determine message metadata, the origin of sourced messages
+// is parsed from the message headers
 func ParseJSMsgMetadata(m *nats.Msg) (info *MsgInfo, err error) {
-	return ParseJSMsgMetadataReply(m.Reply)
+	info, err = ParseJSMsgMetadataReply(m.Reply)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Header != nil {
+		info.oStream, info.oSeq, _ = parseStreamSourceHeader(m.Header.Get(api.JSStreamSource))
+	}
+
+	return info, nil
+}
+
+// parseStreamSourceHeader parses the Nats-Stream-Source header in either the current format of origin stream name,
+// sequence, filter and transform separated by spaces or the original format holding the ack reply subject of the
+// origin message. Stream names of external sources carry a hash of the external API prefix which is removed
+func parseStreamSourceHeader(hdr string) (stream string, seq uint64, ok bool) {
+	if hdr == "" {
+		return "", 0, false
+	}
+
+	if strings.HasPrefix(hdr, "$JS.ACK.") {
+		nfo, err := ParseJSMsgMetadataReply(hdr)
+		if err != nil {
+			return "", 0, false
+		}
+
+		return nfo.Stream(), nfo.StreamSequence(), true
+	}
+
+	fields := strings.Fields(hdr)
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+
+	seq, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	stream, _, _ = strings.Cut(fields[0], ":")
+
+	return stream, seq, true
 }
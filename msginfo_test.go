@@ -71,3 +71,32 @@ func TestParseJSMsgMetadata_New(t *testing.T) {
 		}
 	}
 }
+
+func TestParseJSMsgMetadata_Origin(t *testing.T) {
+	cases := []struct {
+		hdr    string
+		stream string
+		seq    uint64
+	}{
+		{"", "", 0},
+		{"ORDERS 10 > >", "ORDERS", 10},
+		{"ORDERS:8a2b4f 10 ORDERS.* >", "ORDERS", 10},
+		{"$JS.ACK.ORDERS.NEW.1.12.3.1587466354254920000.10", "ORDERS", 12},
+		{"ORDERS", "", 0},
+	}
+
+	for _, tc := range cases {
+		msg := nats.NewMsg("x")
+		msg.Reply = "$JS.ACK.AGG.NEW.1.2.3.1587466354254920000.10"
+		if tc.hdr != "" {
+			msg.Header.Set("Nats-Stream-Source", tc.hdr)
+		}
+
+		i, err := jsm.ParseJSMsgMetadata(msg)
+		checkErr(t, err, "msg parse failed")
+
+		if i.OriginStream() != tc.stream || i.OriginSequence() != tc.seq {
+			t.Fatalf("expected %s %d for %q got %s %d", tc.stream, tc.seq, tc.hdr, i.OriginStream(), i.OriginSequence())
+		}
+	}
+}
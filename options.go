@@ -16,6 +16,8 @@ package jsm
 import (
 	"time"
 
+	"github.com/nats-io/nats.go"
+
 	"github.com/nats-io/jsm.go/api"
 )
 
@@ -63,3 +65,114 @@ func WithDomain(d string) Option {
 		o.domain = d
 	}
 }
+
+// WithFilterCoverageCheck verifies that the filter subjects of new consumers match at least one subject
+// of their stream, this requires an additional API call to load the stream during create
+func WithFilterCoverageCheck() Option {
+	return func(o *Manager) {
+		o.filterCoverageCheck = true
+	}
+}
+
+// WithDefaultConsumerMetadata adds metadata to every consumer created using the manager, keys already set in the consumer metadata are not changed
+func WithDefaultConsumerMetadata(meta map[string]string) Option {
+	return func(o *Manager) {
+		o.consumerMetadata = make(map[string]string, len(meta))
+		for k, v := range meta {
+			o.consumerMetadata[k] = v
+		}
+	}
+}
+
+// WithMinInactiveThreshold ensures every ephemeral consumer created using the manager has an inactive threshold of at
+// least d, larger thresholds set when creating the consumer are kept and durable consumers are not changed
+func WithMinInactiveThreshold(d time.Duration) Option {
+	return func(o *Manager) {
+		o.minInactiveThreshold = d
+	}
+}
+
+// WithConsumerMetadataLimit sets the maximum combined size in bytes of metadata keys and values, creating or updating
+// consumers with larger metadata fails before the request is sent. The limit can not be raised above the server limit
+// of 128KiB, 0 uses the server limit
+func WithConsumerMetadataLimit(size int) Option {
+	return func(o *Manager) {
+		o.consumerMetadataLimit = size
+	}
+}
+
+// WithReservedConsumerMetadata allows consumer metadata keys starting with _nats., which are reserved for the server, to be set
+func WithReservedConsumerMetadata() Option {
+	return func(o *Manager) {
+		o.reservedConsumerMetadata = true
+	}
+}
+
+// ManagerOptions is a serializable snapshot of the settings of a Manager, the connection and any API validator are not included
+type ManagerOptions struct {
+	Timeout                  time.Duration     `json:"timeout"`
+	Trace                    bool              `json:"trace,omitempty"`
+	APIPrefix                string            `json:"api_prefix,omitempty"`
+	EventPrefix              string            `json:"event_prefix,omitempty"`
+	Domain                   string            `json:"domain,omitempty"`
+	FilterCoverageCheck      bool              `json:"filter_coverage_check,omitempty"`
+	DefaultConsumerMetadata  map[string]string `json:"default_consumer_metadata,omitempty"`
+	MinInactiveThreshold     time.Duration     `json:"min_inactive_threshold,omitempty"`
+	ConsumerMetadataLimit    int               `json:"consumer_metadata_limit,omitempty"`
+	ReservedConsumerMetadata bool              `json:"reserved_consumer_metadata,omitempty"`
+}
+
+// Options is a snapshot of the settings the Manager was created with, see NewManagerFromOptions()
+func (m *Manager) Options() ManagerOptions {
+	opts := ManagerOptions{
+		Timeout:                  m.timeout,
+		Trace:                    m.trace,
+		APIPrefix:                m.apiPrefix,
+		EventPrefix:              m.eventPrefix,
+		Domain:                   m.domain,
+		FilterCoverageCheck:      m.filterCoverageCheck,
+		MinInactiveThreshold:     m.minInactiveThreshold,
+		ConsumerMetadataLimit:    m.consumerMetadataLimit,
+		ReservedConsumerMetadata: m.reservedConsumerMetadata,
+	}
+
+	if len(m.consumerMetadata) > 0 {
+		opts.DefaultConsumerMetadata = make(map[string]string, len(m.consumerMetadata))
+		for k, v := range m.consumerMetadata {
+			opts.DefaultConsumerMetadata[k] = v
+		}
+	}
+
+	return opts
+}
+
+// NewManagerFromOptions creates a Manager using nc with the settings in opts as produced by Manager.Options()
+func NewManagerFromOptions(nc *nats.Conn, opts ManagerOptions) (*Manager, error) {
+	mopts := []Option{
+		WithTimeout(opts.Timeout),
+		WithAPIPrefix(opts.APIPrefix),
+		WithEventPrefix(opts.EventPrefix),
+		WithDomain(opts.Domain),
+	}
+
+	if opts.Trace {
+		mopts = append(mopts, WithTrace())
+	}
+	if opts.FilterCoverageCheck {
+		mopts = append(mopts, WithFilterCoverageCheck())
+	}
+	if len(opts.DefaultConsumerMetadata) > 0 {
+		mopts = append(mopts, WithDefaultConsumerMetadata(opts.DefaultConsumerMetadata))
+	}
+	if opts.MinInactiveThreshold > 0 {
+		mopts = append(mopts, WithMinInactiveThreshold(opts.MinInactiveThreshold))
+	}
+	if opts.ConsumerMetadataLimit > 0 {
+		mopts = append(mopts, WithConsumerMetadataLimit(opts.ConsumerMetadataLimit))
+	}
+	if opts.ReservedConsumerMetadata {
+		mopts = append(mopts, WithReservedConsumerMetadata())
+	}
+
+	return New(nc, mopts...)
+}
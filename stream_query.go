@@ -496,6 +496,84 @@ func SubjectIsSubsetMatch(subject, test string) bool {
 	return isSubsetMatch(tts, test)
 }
 
+// subjectsOverlap determines if any subject could match both a and b, wildcards are supported in both
+func subjectsOverlap(a, b string) bool {
+	tsa := [32]string{}
+	tsb := [32]string{}
+	at := tokenizeSubjectIntoSlice(tsa[:0], a)
+	bt := tokenizeSubjectIntoSlice(tsb[:0], b)
+
+	for i := 0; i < len(at) && i < len(bt); i++ {
+		t1 := at[i]
+		t2 := bt[i]
+
+		if t1 == string(fwc) || t2 == string(fwc) {
+			return true
+		}
+
+		if t1 == string(pwc) || t2 == string(pwc) {
+			continue
+		}
+
+		if t1 != t2 {
+			return false
+		}
+	}
+
+	return len(at) == len(bt)
+}
+
+// subjectIntersection is the subject pattern matching exactly the subjects matched by both a and b, ok is false when
+// the patterns do not overlap
+func subjectIntersection(a, b string) (subject string, ok bool) {
+	tsa := [32]string{}
+	tsb := [32]string{}
+	at := tokenizeSubjectIntoSlice(tsa[:0], a)
+	bt := tokenizeSubjectIntoSlice(tsb[:0], b)
+
+	var tokens []string
+
+	for i := 0; i < len(at) && i < len(bt); i++ {
+		t1 := at[i]
+		t2 := bt[i]
+
+		switch {
+		case t1 == string(fwc):
+			return strings.Join(append(tokens, bt[i:]...), string(btsep)), true
+		case t2 == string(fwc):
+			return strings.Join(append(tokens, at[i:]...), string(btsep)), true
+		case t1 == string(pwc):
+			tokens = append(tokens, t2)
+		case t2 == string(pwc), t1 == t2:
+			tokens = append(tokens, t1)
+		default:
+			return "", false
+		}
+	}
+
+	if len(at) != len(bt) {
+		return "", false
+	}
+
+	return strings.Join(tokens, string(btsep)), true
+}
+
+// WouldLoop determines if messages delivered to deliverSubject would be stored by a stream capturing streamSubjects,
+// which would cause each delivery to store a new message in the stream. Wildcards are supported on both sides
+func WouldLoop(streamSubjects []string, deliverSubject string) bool {
+	if deliverSubject == "" {
+		return false
+	}
+
+	for _, subject := range streamSubjects {
+		if subjectsOverlap(subject, deliverSubject) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // This will test a subject as an array of tokens against a test subject
 // Calls into the function isSubsetMatchTokenized
 func isSubsetMatch(tokens []string, test string) bool {
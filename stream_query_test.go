@@ -237,3 +237,27 @@ func TestStreamSubjectWildcardMatch(t *testing.T) {
 		checkStreamQueryMatched(t, mgr, 1, jsm.StreamQuerySubjectWildcard("in.*.*.>"), jsm.StreamQueryInvert())
 	})
 }
+
+func TestWouldLoop(t *testing.T) {
+	cases := []struct {
+		subjects []string
+		deliver  string
+		loops    bool
+	}{
+		{[]string{"ORDERS.>"}, "ORDERS.deliver", true},
+		{[]string{"ORDERS.*"}, "ORDERS.deliver.x", false},
+		{[]string{"ORDERS.new", "ORDERS.*"}, "ORDERS.deliver", true},
+		{[]string{"ORDERS.>"}, "out", false},
+		{[]string{"ORDERS.new"}, "ORDERS.*", true},
+		{[]string{"ORDERS.new"}, ">", true},
+		{[]string{"*.new"}, "ORDERS.*", true},
+		{[]string{"ORDERS.>"}, "", false},
+		{nil, "ORDERS.new", false},
+	}
+
+	for _, c := range cases {
+		if jsm.WouldLoop(c.subjects, c.deliver) != c.loops {
+			t.Fatalf("expected %v for %v and %q", c.loops, c.subjects, c.deliver)
+		}
+	}
+}
@@ -120,7 +120,7 @@ func (m *Manager) LoadOrNewStreamFromDefault(name string, dflt api.StreamConfig,
 		o(&dflt)
 	}
 	s, err := m.LoadStream(name)
-	if IsNatsError(err, 10059) {
+	if IsStreamNotFoundErr(err) {
 		return m.NewStreamFromDefault(name, dflt)
 	}
 
@@ -190,8 +190,12 @@ func (m *Manager) loadConfigForStream(stream *Stream) (err error) {
 }
 
 func (m *Manager) loadStreamInfo(stream string, req *api.JSApiStreamInfoRequest) (info *api.StreamInfo, err error) {
+	return m.loadStreamInfoWithContext(context.Background(), stream, req)
+}
+
+func (m *Manager) loadStreamInfoWithContext(ctx context.Context, stream string, req *api.JSApiStreamInfoRequest) (info *api.StreamInfo, err error) {
 	var resp api.JSApiStreamInfoResponse
-	err = m.jsonRequest(fmt.Sprintf(api.JSApiStreamInfoT, stream), req, &resp)
+	err = m.jsonRequestWithContext(ctx, fmt.Sprintf(api.JSApiStreamInfoT, stream), req, &resp)
 	if err != nil {
 		return nil, err
 	}